@@ -0,0 +1,224 @@
+package nonota
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const backupTimeFormat = "20060102-150405"
+
+// writeFileAtomic writes the contents generated by write into filename in a
+// crash-safe way: data is written to a temporary file in the same dir, synced
+// to disk and then renamed over the original file. Either the old or the new
+// contents are ever visible at filename, never a partially written file.
+func writeFileAtomic(filename string, write func(w io.Writer) error) error {
+	dir, base := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(filename); err == nil {
+		mode = fi.Mode().Perm()
+	}
+
+	f, err := ioutil.TempFile(dir, "."+base+".tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := f.Name()
+	removeTmp := true
+	defer func() {
+		if removeTmp {
+			os.Remove(tmpName)
+		}
+	}()
+
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpName, filename); err != nil {
+		return err
+	}
+	removeTmp = false
+
+	// Sync the dir so that the rename itself is durable. Not every platform
+	// supports this, so errors are ignored.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return nil
+}
+
+// backupPattern returns the glob pattern matching all backups of the given
+// file.
+func backupPattern(filename string) string {
+	return filename + ".*.bak"
+}
+
+// backupName returns the name of the backup of the given file taken at the
+// given time.
+func backupName(filename string, t time.Time) string {
+	return fmt.Sprintf("%s.%s.bak", filename, t.Format(backupTimeFormat))
+}
+
+// ListBackups returns the existing backups of the given file, sorted from the
+// newest to the oldest one.
+func ListBackups(filename string) ([]string, error) {
+	matches, err := filepath.Glob(backupPattern(filename))
+	if err != nil {
+		return nil, err
+	}
+
+	// Only consider files with a valid timestamp, given the pattern may
+	// match unrelated files.
+	prefix, suffix := filename+".", ".bak"
+	backups := matches[:0]
+	for _, m := range matches {
+		ts := strings.TrimSuffix(strings.TrimPrefix(m, prefix), suffix)
+		if _, err := time.Parse(backupTimeFormat, ts); err != nil {
+			continue
+		}
+		backups = append(backups, m)
+	}
+
+	// The timestamp format sorts lexicographically.
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups, nil
+}
+
+// BackupInterval is the minimum time between the backups taken by a storage
+// when saving a board. The first save of every storage always takes one, so
+// each session of nonota can be reverted as a whole.
+const BackupInterval = time.Hour
+
+// BackupTimer tracks when a storage last backed up its board, so that backups
+// are taken at most once every BackupInterval.
+type BackupTimer struct {
+	// Keep is the number of backups to keep.
+	Keep int
+
+	last time.Time
+}
+
+// Due returns the number of backups to keep if a backup should be taken at the
+// given time (recording it as the time of the last backup) or zero if not.
+func (bt *BackupTimer) Due(now time.Time) int {
+	if bt.Keep <= 0 {
+		return 0
+	}
+	if !bt.last.IsZero() && now.Sub(bt.last) < BackupInterval {
+		return 0
+	}
+	bt.last = now
+	return bt.Keep
+}
+
+// BackupFile stores the current contents of filename as a new timestamped
+// backup and removes the oldest backups so that at most keep of them remain.
+// Nothing is done if the file does not exist yet or if keep is <= 0.
+func BackupFile(filename string, keep int) error {
+	return backupFile(filename, keep, time.Now(), func(backup string) error {
+		// Hard link the current file so that the backup is the exact
+		// file that is about to be replaced. Fallback to copying if
+		// the filesystem doesn't support links.
+		if err := os.Link(filename, backup); err != nil {
+			return copyFile(filename, backup)
+		}
+		return nil
+	})
+}
+
+// BackupFileWith is like BackupFile, but the backup is created by calling
+// create with the name of the backup file. It's meant for files that are
+// modified in place (such as databases), which can't be hard linked.
+func BackupFileWith(filename string, keep int, create func(backup string) error) error {
+	return backupFile(filename, keep, time.Now(), create)
+}
+
+func backupFile(filename string, keep int, now time.Time, create func(backup string) error) error {
+	if keep <= 0 {
+		return nil
+	}
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return nil
+	}
+
+	backup := backupName(filename, now)
+	if _, err := os.Stat(backup); os.IsNotExist(err) {
+		if err := create(backup); err != nil {
+			return fmt.Errorf("unable to backup %s: %v", filename, err)
+		}
+	}
+
+	return PruneBackups(filename, keep)
+}
+
+// PruneBackups removes the oldest backups of filename so that at most keep of
+// them remain.
+func PruneBackups(filename string, keep int) error {
+	backups, err := ListBackups(filename)
+	if err != nil {
+		return err
+	}
+	for i := keep; i < len(backups); i++ {
+		if err := os.Remove(backups[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RestoreBackup atomically replaces the contents of filename with the
+// contents of the given backup file.
+func RestoreBackup(filename, backup string) error {
+	lock, err := LockBoard(filename, true)
+	if err != nil {
+		return fmt.Errorf("unable to lock %s: %v", filename, err)
+	}
+	defer lock.Unlock()
+
+	src, err := os.Open(backup)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	return writeFileAtomic(filename, func(w io.Writer) error {
+		_, err := io.Copy(w, src)
+		return err
+	})
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	return writeFileAtomic(dst, func(w io.Writer) error {
+		_, err := io.Copy(w, in)
+		return err
+	})
+}
@@ -0,0 +1,203 @@
+package nonota
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nonota")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "board.yml")
+
+	write := func(data string) func(w io.Writer) error {
+		return func(w io.Writer) error {
+			_, err := io.WriteString(w, data)
+			return err
+		}
+	}
+	if err := writeFileAtomic(filename, write("first")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filename, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// A failed write leaves the previous contents and no temporary files.
+	errWrite := errors.New("write failed")
+	err = writeFileAtomic(filename, func(w io.Writer) error {
+		io.WriteString(w, "partial")
+		return errWrite
+	})
+	if err != errWrite {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if data, _ := ioutil.ReadFile(filename); string(data) != "first" {
+		t.Fatalf("unexpected contents after failed write %q", data)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Fatalf("unexpected files after failed write %v", files)
+	}
+
+	// The mode of the file is kept.
+	if err := writeFileAtomic(filename, write("second")); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadFile(filename); string(data) != "second" {
+		t.Fatalf("unexpected contents %q", data)
+	}
+	if fi, err := os.Stat(filename); err != nil || fi.Mode().Perm() != 0600 {
+		t.Fatalf("unexpected mode %v (%v)", fi.Mode(), err)
+	}
+}
+
+func TestBackupRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nonota")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "board.yml")
+
+	// Nothing to backup yet.
+	if err := BackupFile(filename, 3); err != nil {
+		t.Fatal(err)
+	}
+	if backups, _ := ListBackups(filename); len(backups) != 0 {
+		t.Fatalf("unexpected backups %v", backups)
+	}
+
+	now := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	link := func(backup string) error { return os.Link(filename, backup) }
+	for i := 0; i < 5; i++ {
+		data := []byte{byte('a' + i)}
+		if err := writeFileAtomic(filename, func(w io.Writer) error {
+			_, err := w.Write(data)
+			return err
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := backupFile(filename, 3, now.Add(time.Duration(i)*time.Minute), link); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the newest backups are kept, newest first.
+	backups, err := ListBackups(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 3 {
+		t.Fatalf("unexpected backups %v", backups)
+	}
+	for i, want := range []string{"e", "d", "c"} {
+		if data, _ := ioutil.ReadFile(backups[i]); string(data) != want {
+			t.Fatalf("unexpected contents of backup %d: %q", i, data)
+		}
+	}
+
+	// Unrelated files matching the pattern are ignored.
+	other := filename + ".notadate.bak"
+	if err := ioutil.WriteFile(other, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := PruneBackups(filename, 1); err != nil {
+		t.Fatal(err)
+	}
+	if backups, _ := ListBackups(filename); len(backups) != 1 {
+		t.Fatalf("unexpected backups after pruning %v", backups)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Fatalf("unrelated file removed: %v", err)
+	}
+}
+
+func TestRestoreBackup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nonota")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "board.yml")
+
+	b := &Board{ID: NewID(), Lists: []*List{{ID: NewID(), Title: "old"}}}
+	if err := BoardToFile(filename, b); err != nil {
+		t.Fatal(err)
+	}
+	if err := BackupFile(filename, 1); err != nil {
+		t.Fatal(err)
+	}
+	b.Lists[0].Title = "new"
+	if err := BoardToFile(filename, b); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := ListBackups(filename)
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("unexpected backups %v (%v)", backups, err)
+	}
+	if err := RestoreBackup(filename, backups[0]); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := BoardFromFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Lists[0].Title != "old" {
+		t.Fatalf("unexpected restored board %#v", restored.Lists[0])
+	}
+
+	// The backup itself is left alone.
+	if _, err := os.Stat(backups[0]); err != nil {
+		t.Fatalf("backup removed by restore: %v", err)
+	}
+}
+
+func TestStorageBackupInterval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nonota")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "board.yml")
+	b := &Board{ID: NewID()}
+	if err := BoardToFile(filename, b); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the first of many saves in a session takes a backup.
+	s := NewYAMLStorage(filename, 10)
+	for i := 0; i < 3; i++ {
+		if err := s.Save(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if backups, _ := ListBackups(filename); len(backups) != 1 {
+		t.Fatalf("unexpected backups %v", backups)
+	}
+
+	// Saves after the interval take another one.
+	bt := BackupTimer{Keep: 10}
+	now := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		after time.Duration
+		keep  int
+	}{
+		{0, 10},
+		{time.Minute, 0},
+		{BackupInterval - time.Second, 0},
+		{BackupInterval, 10},
+	} {
+		if keep := bt.Due(now.Add(tc.after)); keep != tc.keep {
+			t.Fatalf("unexpected backups after %s: %d", tc.after, keep)
+		}
+	}
+}
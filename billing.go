@@ -0,0 +1,185 @@
+package nonota
+
+import (
+	"fmt"
+	"time"
+)
+
+// Billing period kinds.
+const (
+	// BillingMonthly periods start every month on the configured start day
+	// (the 1st by default).
+	BillingMonthly = "monthly"
+
+	// BillingSemiMonthly periods run from the 1st to the 15th and from the
+	// 16th to the end of every month.
+	BillingSemiMonthly = "semimonthly"
+
+	// BillingBiWeekly periods last 14 days, starting on the anchor date.
+	BillingBiWeekly = "biweekly"
+
+	// BillingFiscal445 periods follow a 4-4-5 fiscal calendar: every
+	// quarter is made up of two 4-week periods followed by a 5-week one,
+	// starting on the anchor date. Fiscal years are always 52 weeks long, so
+	// the anchor needs to be moved forward on 53-week years.
+	BillingFiscal445 = "fiscal445"
+)
+
+// dateFormat is the format of dates in the board settings.
+const dateFormat = "2006-01-02"
+
+// BillingPeriod defines how time is split into billing periods.
+//
+// As with the other periods, Start returns the first second of a billing
+// period and End its last second.
+type BillingPeriod struct {
+	// Kind is one of the Billing* constants. Empty means monthly.
+	Kind string `yaml:",omitempty"`
+
+	// StartDay is the day of the month on which monthly periods start. On
+	// shorter months, periods start on the last day of the month instead.
+	StartDay int `yaml:",omitempty"`
+
+	// Anchor is the date (in the YYYY-MM-DD format) on which one of the
+	// periods started, used by bi-weekly and fiscal periods.
+	Anchor string `yaml:",omitempty"`
+}
+
+// Validate returns an error if the billing period is not correctly configured.
+func (p BillingPeriod) Validate() error {
+	switch p.Kind {
+	case "", BillingMonthly:
+		if p.StartDay < 0 || p.StartDay > 31 {
+			return fmt.Errorf("invalid billing start day %d", p.StartDay)
+		}
+	case BillingSemiMonthly:
+	case BillingBiWeekly, BillingFiscal445:
+		if p.Anchor == "" {
+			return fmt.Errorf("%s billing periods need an anchor date", p.Kind)
+		}
+		if _, err := time.Parse(dateFormat, p.Anchor); err != nil {
+			return fmt.Errorf("invalid billing anchor date: %v", err)
+		}
+	default:
+		return fmt.Errorf("unknown billing period %q", p.Kind)
+	}
+	return nil
+}
+
+// anchor returns the anchor date of the period in the given location. Invalid
+// anchors are treated as the zero date, so that the period functions always
+// return something sensible.
+func (p BillingPeriod) anchor(loc *time.Location) time.Time {
+	anchor, _ := time.ParseInLocation(dateFormat, p.Anchor, loc)
+	return anchor
+}
+
+// Start returns the start of the billing period that contains t.
+func (p BillingPeriod) Start(t time.Time) time.Time {
+	switch p.Kind {
+	case BillingSemiMonthly:
+		day := 1
+		if t.Day() > 15 {
+			day = 16
+		}
+		return time.Date(t.Year(), t.Month(), day, 0, 0, 0, 0, t.Location())
+
+	case BillingBiWeekly:
+		anchor := p.anchor(t.Location())
+		days := floorDiv(daysBetween(anchor, t), 14) * 14
+		return anchor.AddDate(0, 0, days)
+
+	case BillingFiscal445:
+		// Fiscal years have 364 days, split into quarters of 91 days.
+		anchor := p.anchor(t.Location())
+		days := daysBetween(anchor, t)
+		year := floorDiv(days, 364) * 364
+		quarter := (days - year) / 91 * 91
+		period := 0
+		switch week := (days - year - quarter) / 7; {
+		case week >= 8:
+			period = 56
+		case week >= 4:
+			period = 28
+		}
+		return anchor.AddDate(0, 0, year+quarter+period)
+
+	default:
+		start := monthDay(t.Year(), t.Month(), p.StartDay, t.Location())
+		if t.Before(start) {
+			start = monthDay(t.Year(), t.Month()-1, p.StartDay, t.Location())
+		}
+		return start
+	}
+}
+
+// Next returns the start of the billing period after the one that contains
+// t.
+func (p BillingPeriod) Next(t time.Time) time.Time {
+	start := p.Start(t)
+	switch p.Kind {
+	case BillingSemiMonthly:
+		if start.Day() == 1 {
+			return start.AddDate(0, 0, 15)
+		}
+		return time.Date(start.Year(), start.Month()+1, 1, 0, 0, 0, 0,
+			start.Location())
+
+	case BillingBiWeekly:
+		return start.AddDate(0, 0, 14)
+
+	case BillingFiscal445:
+		// The last period of every quarter has 5 weeks.
+		days := daysBetween(p.anchor(start.Location()), start)
+		if (days-floorDiv(days, 364)*364)%91 == 56 {
+			return start.AddDate(0, 0, 35)
+		}
+		return start.AddDate(0, 0, 28)
+
+	default:
+		return monthDay(start.Year(), start.Month()+1, p.StartDay,
+			start.Location())
+	}
+}
+
+// Previous returns the start of the billing period before the one that
+// contains t.
+func (p BillingPeriod) Previous(t time.Time) time.Time {
+	return p.Start(p.Start(t).AddDate(0, 0, -1))
+}
+
+// End returns the end of the billing period that contains t.
+func (p BillingPeriod) End(t time.Time) time.Time {
+	return EndOfDay(p.Next(t).AddDate(0, 0, -1))
+}
+
+// monthDay returns the start of the given day of the given month, or of the
+// last day of the month if it has fewer days. Day 0 is the same as the 1st.
+func monthDay(year int, month time.Month, day int, loc *time.Location) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	if day <= 1 {
+		return first
+	}
+	last := first.AddDate(0, 1, -1).Day()
+	if day > last {
+		day = last
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, loc)
+}
+
+// daysBetween returns the number of calendar days from the date of a to the
+// date of b. It's not affected by DST changes.
+func daysBetween(a, b time.Time) int {
+	da := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	db := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(db.Sub(da) / (24 * time.Hour))
+}
+
+// floorDiv returns a/b rounded towards negative infinity.
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
@@ -0,0 +1,132 @@
+package nonota
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBillingPeriods(t *testing.T) {
+	const format = "2006-01-02 15:04:05"
+
+	type testCase struct {
+		period BillingPeriod
+		t      string
+		start  string
+		end    string
+		prev   string
+		next   string
+	}
+
+	monthly := BillingPeriod{}
+	day15 := BillingPeriod{Kind: BillingMonthly, StartDay: 15}
+	day31 := BillingPeriod{Kind: BillingMonthly, StartDay: 31}
+	semi := BillingPeriod{Kind: BillingSemiMonthly}
+	biweekly := BillingPeriod{Kind: BillingBiWeekly, Anchor: "2019-01-07"}
+	fiscal := BillingPeriod{Kind: BillingFiscal445, Anchor: "2018-12-30"}
+
+	testCases := []testCase{
+		// Calendar months.
+		{monthly, "2019-02-23 10:20:23", "2019-02-01 00:00:00", "2019-02-28 23:59:59",
+			"2019-01-01 00:00:00", "2019-03-01 00:00:00"},
+		{monthly, "2019-12-01 00:00:00", "2019-12-01 00:00:00", "2019-12-31 23:59:59",
+			"2019-11-01 00:00:00", "2020-01-01 00:00:00"},
+		{monthly, "2020-02-29 23:59:59", "2020-02-01 00:00:00", "2020-02-29 23:59:59",
+			"2020-01-01 00:00:00", "2020-03-01 00:00:00"},
+
+		// Months starting on the 15th.
+		{day15, "2019-03-20 10:20:23", "2019-03-15 00:00:00", "2019-04-14 23:59:59",
+			"2019-02-15 00:00:00", "2019-04-15 00:00:00"},
+		{day15, "2019-03-10 10:20:23", "2019-02-15 00:00:00", "2019-03-14 23:59:59",
+			"2019-01-15 00:00:00", "2019-03-15 00:00:00"},
+		{day15, "2019-03-15 00:00:00", "2019-03-15 00:00:00", "2019-04-14 23:59:59",
+			"2019-02-15 00:00:00", "2019-04-15 00:00:00"},
+		{day15, "2019-01-14 23:59:59", "2018-12-15 00:00:00", "2019-01-14 23:59:59",
+			"2018-11-15 00:00:00", "2019-01-15 00:00:00"},
+
+		// Months starting on the 31st start on the last day of shorter
+		// months.
+		{day31, "2019-02-28 10:20:23", "2019-02-28 00:00:00", "2019-03-30 23:59:59",
+			"2019-01-31 00:00:00", "2019-03-31 00:00:00"},
+		{day31, "2019-03-30 10:20:23", "2019-02-28 00:00:00", "2019-03-30 23:59:59",
+			"2019-01-31 00:00:00", "2019-03-31 00:00:00"},
+
+		// Semi-monthly.
+		{semi, "2019-02-10 10:20:23", "2019-02-01 00:00:00", "2019-02-15 23:59:59",
+			"2019-01-16 00:00:00", "2019-02-16 00:00:00"},
+		{semi, "2019-02-20 10:20:23", "2019-02-16 00:00:00", "2019-02-28 23:59:59",
+			"2019-02-01 00:00:00", "2019-03-01 00:00:00"},
+		{semi, "2019-12-31 23:59:59", "2019-12-16 00:00:00", "2019-12-31 23:59:59",
+			"2019-12-01 00:00:00", "2020-01-01 00:00:00"},
+
+		// Bi-weekly.
+		{biweekly, "2019-01-07 00:00:00", "2019-01-07 00:00:00", "2019-01-20 23:59:59",
+			"2018-12-24 00:00:00", "2019-01-21 00:00:00"},
+		{biweekly, "2019-03-15 10:20:23", "2019-03-04 00:00:00", "2019-03-17 23:59:59",
+			"2019-02-18 00:00:00", "2019-03-18 00:00:00"},
+		{biweekly, "2018-12-30 10:20:23", "2018-12-24 00:00:00", "2019-01-06 23:59:59",
+			"2018-12-10 00:00:00", "2019-01-07 00:00:00"},
+
+		// 4-4-5 fiscal calendar.
+		{fiscal, "2018-12-30 00:00:00", "2018-12-30 00:00:00", "2019-01-26 23:59:59",
+			"2018-11-25 00:00:00", "2019-01-27 00:00:00"},
+		{fiscal, "2019-03-01 10:20:23", "2019-02-24 00:00:00", "2019-03-30 23:59:59",
+			"2019-01-27 00:00:00", "2019-03-31 00:00:00"},
+		{fiscal, "2019-04-10 10:20:23", "2019-03-31 00:00:00", "2019-04-27 23:59:59",
+			"2019-02-24 00:00:00", "2019-04-28 00:00:00"},
+	}
+
+	parse := func(s string) time.Time {
+		res, err := time.ParseInLocation(format, s, time.Local)
+		if err != nil {
+			t.Fatalf("unable to decode test time %s: %v", s, err)
+		}
+		return res
+	}
+
+	for i, tc := range testCases {
+		testTime := parse(tc.t)
+		checks := []struct {
+			name     string
+			expected time.Time
+			actual   time.Time
+		}{
+			{"start", parse(tc.start), tc.period.Start(testTime)},
+			{"end", parse(tc.end), tc.period.End(testTime)},
+			{"prev", parse(tc.prev), tc.period.Previous(testTime)},
+			{"next", parse(tc.next), tc.period.Next(testTime)},
+		}
+		for _, c := range checks {
+			if !c.actual.Equal(c.expected) {
+				t.Fatalf("%d (tc %s %s %s): expected %s found %s", i,
+					tc.period.Kind, tc.t, c.name, c.expected, c.actual)
+			}
+		}
+	}
+}
+
+func TestBillingPeriodValidate(t *testing.T) {
+	type testCase struct {
+		period BillingPeriod
+		valid  bool
+	}
+	testCases := []testCase{
+		{BillingPeriod{}, true},
+		{BillingPeriod{Kind: BillingMonthly, StartDay: 15}, true},
+		{BillingPeriod{Kind: BillingMonthly, StartDay: 32}, false},
+		{BillingPeriod{Kind: BillingSemiMonthly}, true},
+		{BillingPeriod{Kind: BillingBiWeekly, Anchor: "2019-01-07"}, true},
+		{BillingPeriod{Kind: BillingBiWeekly}, false},
+		{BillingPeriod{Kind: BillingFiscal445, Anchor: "2019-13-07"}, false},
+		{BillingPeriod{Kind: "yearly"}, false},
+	}
+
+	for i, tc := range testCases {
+		err := tc.period.Validate()
+		if tc.valid && err != nil {
+			t.Fatalf("%d: unexpected error %v", i, err)
+		}
+		if !tc.valid && err == nil {
+			t.Fatalf("%d: expected an error", i)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package nonota
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 )
 
 type TaskTime struct {
+	ID    string
 	Start time.Time
 	End   time.Time
 	Note  string
@@ -16,31 +18,115 @@ type TaskTime struct {
 	// Duration is *not* end-start; rather, it's how much work was recorded
 	// within that timeframe.
 	Duration time.Duration
+
+	// Pomodoros is the number of focus sessions completed during the entry.
+	Pomodoros int `yaml:",omitempty"`
 }
 
 type Task struct {
+	ID          string
 	Title       string
 	Description string
 	Times       []*TaskTime
+	Tags        []string `yaml:",omitempty"`
+
+	// Archived tasks are hidden from the board but their times are still
+	// accounted for.
+	Archived bool `yaml:",omitempty"`
 }
 
 func (t *Task) AddTaskTime(tt *TaskTime) {
+	if tt.ID == "" {
+		tt.ID = NewID()
+	}
 	t.Times = append(t.Times, tt)
 }
 
+// TaskTimeByID returns the time entry of the task with the given id or nil
+// if it doesn't exist.
+func (t *Task) TaskTimeByID(id string) *TaskTime {
+	if id == "" {
+		return nil
+	}
+	for _, tt := range t.Times {
+		if tt.ID == id {
+			return tt
+		}
+	}
+	return nil
+}
+
+// DurationWithin returns how much of the work recorded in the entry falls
+// within the given period.
+//
+// Entries that straddle the boundaries of the period are prorated: the
+// recorded duration is assumed to be evenly spread between Start and End, so
+// the period gets the same fraction of the duration as the fraction of the
+// entry's timeframe it overlaps. Proration is done such that the parts of an
+// entry split between consecutive periods always add up to its full duration.
+//
+// Entries without a timeframe (End not after Start) are counted fully in the
+// period that contains their Start.
+func (tt *TaskTime) DurationWithin(fromTime, toTime time.Time) time.Duration {
+	toTime = periodEnd(toTime)
+	span := tt.End.Sub(tt.Start)
+	if span <= 0 {
+		if tt.Start.Before(fromTime) || !tt.Start.Before(toTime) {
+			return 0
+		}
+		return tt.Duration
+	}
+
+	start, end := tt.Start, tt.End
+	if start.Before(fromTime) {
+		start = fromTime
+	}
+	if end.After(toTime) {
+		end = toTime
+	}
+	if !end.After(start) {
+		return 0
+	}
+
+	// Compute the duration up to the end and subtract the duration up to
+	// the start (instead of scaling the overlap directly) so that rounding
+	// errors don't accumulate when splitting an entry.
+	return scaleDuration(tt.Duration, end.Sub(tt.Start), span) -
+		scaleDuration(tt.Duration, start.Sub(tt.Start), span)
+}
+
+// PomodorosWithin returns the number of pomodoros of the entries of the task
+// that started within the given period.
+func (t *Task) PomodorosWithin(fromTime, toTime time.Time) int {
+	toTime = periodEnd(toTime)
+	var total int
+	for _, tt := range t.Times {
+		if !tt.Start.Before(fromTime) && tt.Start.Before(toTime) {
+			total += tt.Pomodoros
+		}
+	}
+	return total
+}
+
+// TotalTime returns the work recorded in the task within the given period. See
+// TaskTime.DurationWithin for how entries crossing the period boundaries are
+// accounted for.
 func (t *Task) TotalTime(fromTime, toTime time.Time) time.Duration {
 	var total time.Duration
 	for _, tt := range t.Times {
-		if tt.Start.After(fromTime) && tt.End.Before(toTime) {
-			total += tt.Duration
-		}
+		total += tt.DurationWithin(fromTime, toTime)
 	}
 	return total
 }
 
 type List struct {
+	ID    string
 	Title string
 	Tasks []*Task
+
+	// Archived lists (and all their tasks) are hidden from the board but
+	// their times are still accounted for.
+	Archived bool `yaml:",omitempty"`
 }
 
 func (l *List) TotalTime(fromTime, toTime time.Time) time.Duration {
@@ -51,8 +137,85 @@ func (l *List) TotalTime(fromTime, toTime time.Time) time.Duration {
 	return total
 }
 
+// Settings is the board-level configuration.
+type Settings struct {
+	// Billing defines the billing periods of the board. By default, they
+	// are calendar months.
+	Billing BillingPeriod `yaml:",omitempty"`
+
+	// WeekStart is the first day of the week. Weeks start on Sunday by
+	// default.
+	WeekStart Weekday `yaml:",omitempty"`
+
+	// TimeZone is the name of the time zone (for example,
+	// America/Sao_Paulo) in which days, weeks and billing periods are
+	// computed. By default, the local time zone of the machine is used.
+	TimeZone string `yaml:",omitempty"`
+
+	// IdleThreshold is how long without any keypress before the user is
+	// considered away. Zero means DefaultIdleThreshold and a negative value
+	// disables idle detection (but not the detection of suspends).
+	IdleThreshold time.Duration `yaml:",omitempty"`
+
+	// Pomodoro configures the pomodoro mode.
+	Pomodoro PomodoroSettings `yaml:",omitempty"`
+}
+
+// IdleThresholdOrDefault returns the configured idle threshold, or the default
+// one if not configured.
+func (s *Settings) IdleThresholdOrDefault() time.Duration {
+	if s.IdleThreshold == 0 {
+		return DefaultIdleThreshold
+	}
+	return s.IdleThreshold
+}
+
+// Validate returns an error if the settings are not valid.
+func (s *Settings) Validate() error {
+	if s.WeekStart < 0 || s.WeekStart > Weekday(time.Saturday) {
+		return fmt.Errorf("invalid week start %d", s.WeekStart)
+	}
+	if s.TimeZone != "" {
+		if _, err := time.LoadLocation(s.TimeZone); err != nil {
+			return fmt.Errorf("invalid time zone: %v", err)
+		}
+	}
+	return s.Billing.Validate()
+}
+
+// Location returns the time zone of the board. Reference times should be
+// converted to it (with time.Time.In) before computing periods, so that the
+// totals of the board don't depend on the time zone of the machine.
+func (s *Settings) Location() *time.Location {
+	if s.TimeZone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// StartOfWeek returns the start of the week that contains t, according to the
+// configured week start.
+func (s *Settings) StartOfWeek(t time.Time) time.Time {
+	return StartOfWeekOn(t, time.Weekday(s.WeekStart))
+}
+
+// EndOfWeek returns the end of the week that contains t, according to the
+// configured week start.
+func (s *Settings) EndOfWeek(t time.Time) time.Time {
+	return EndOfWeekOn(t, time.Weekday(s.WeekStart))
+}
+
 type Board struct {
-	Lists []*List
+	// Version is the schema version of the board, as stored in its file.
+	Version int
+
+	ID       string
+	Settings Settings `yaml:",omitempty"`
+	Lists    []*List
 }
 
 func (b *Board) MoveUp(list *List) {
@@ -124,28 +287,98 @@ func (b *Board) MoveTaskDown(task *Task) {
 	}
 }
 
-func (b *Board) AppendNewTask(list *List) {
+func (b *Board) AppendNewTask(list *List) *Task {
 	newTask := &Task{
+		ID:    NewID(),
 		Title: "New Task",
 	}
 	list.Tasks = append(list.Tasks, newTask)
+	return newTask
 }
 
-func (b *Board) AppendNewList() {
+func (b *Board) AppendNewList() *List {
 	newList := &List{
+		ID:    NewID(),
 		Title: "New List",
 	}
 	b.Lists = append(b.Lists, newList)
+	return newList
 }
 
-func (b *Board) PrependNewList() {
+func (b *Board) PrependNewList() *List {
 	newList := &List{
+		ID:    NewID(),
 		Title: "New List",
 	}
 	newLists := make([]*List, 0, len(b.Lists)+1)
 	newLists = append(newLists, newList)
 	newLists = append(newLists, b.Lists...)
 	b.Lists = newLists
+	return newList
+}
+
+// ListOfTask returns the list that contains the given task or nil if the task
+// is not in the board.
+func (b *Board) ListOfTask(task *Task) *List {
+	for _, l := range b.Lists {
+		for _, t := range l.Tasks {
+			if t == task {
+				return l
+			}
+		}
+	}
+	return nil
+}
+
+// DeleteList removes the list and all its tasks (including their recorded
+// times) from the board.
+func (b *Board) DeleteList(list *List) {
+	for i, l := range b.Lists {
+		if l == list {
+			b.Lists = append(b.Lists[:i], b.Lists[i+1:]...)
+			return
+		}
+	}
+}
+
+// DeleteTask removes the task (including its recorded times) from the board.
+func (b *Board) DeleteTask(task *Task) {
+	l := b.ListOfTask(task)
+	if l == nil {
+		return
+	}
+	for i, t := range l.Tasks {
+		if t == task {
+			l.Tasks = append(l.Tasks[:i], l.Tasks[i+1:]...)
+			return
+		}
+	}
+}
+
+// ArchiveList hides the list (and its tasks) from the board, while keeping
+// its recorded times for historical reports.
+func (b *Board) ArchiveList(list *List) {
+	list.Archived = true
+}
+
+// RestoreList restores a previously archived list.
+func (b *Board) RestoreList(list *List) {
+	list.Archived = false
+}
+
+// ArchiveTask hides the task from the board, while keeping its recorded times
+// for historical reports.
+func (b *Board) ArchiveTask(task *Task) {
+	task.Archived = true
+}
+
+// RestoreTask restores a previously archived task. If its list is archived,
+// the list is also restored.
+func (b *Board) RestoreTask(task *Task) {
+	task.Archived = false
+	if l := b.ListOfTask(task); l != nil {
+		l.Archived = false
+	}
 }
 
 func (b *Board) TotalTime(fromTime, toTime time.Time) time.Duration {
@@ -156,33 +389,107 @@ func (b *Board) TotalTime(fromTime, toTime time.Time) time.Duration {
 	return total
 }
 
+// TaskByID returns the task with the given id or nil if it doesn't exist in
+// the board.
+func (b *Board) TaskByID(id string) *Task {
+	for _, l := range b.Lists {
+		for _, t := range l.Tasks {
+			if t.ID == id {
+				return t
+			}
+		}
+	}
+	return nil
+}
+
+// ListByID returns the list with the given id or nil if it doesn't exist in
+// the board.
+func (b *Board) ListByID(id string) *List {
+	for _, l := range b.Lists {
+		if l.ID == id {
+			return l
+		}
+	}
+	return nil
+}
+
 func BoardFromFile(filename string) (*Board, error) {
+	lock, err := LockBoard(filename, false)
+	if err != nil {
+		return nil, fmt.Errorf("unable to lock %s: %v", filename, err)
+	}
+	defer lock.Unlock()
+
 	f, err := os.Open(filename)
 	if os.IsNotExist(err) {
-		return &Board{}, nil
+		return &Board{Version: SchemaVersion, ID: NewID()}, nil
 	}
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
 
-	board := &Board{}
+	// The file is first decoded generically, so that older versions can be
+	// migrated to the current one before being decoded into a board.
+	doc := make(yamlDoc)
 	dec := yaml.NewDecoder(f)
-	err = dec.Decode(board)
+	err = dec.Decode(&doc)
 	if err != nil {
 		return nil, fmt.Errorf("error decoding file %s: %v", filename, err)
 	}
+	if err := migrateDoc(doc); err != nil {
+		return nil, fmt.Errorf("error loading file %s: %v", filename, err)
+	}
+
+	board := &Board{}
+	if err := remarshal(doc, board); err != nil {
+		return nil, fmt.Errorf("error decoding file %s: %v", filename, err)
+	}
+	if err := board.Settings.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid settings in %s: %v", filename, err)
+	}
+
+	// Boards created before ids were introduced get them on load so that
+	// they are persisted on the next save.
+	board.ensureIDs()
 
 	return board, nil
 }
 
+// BoardToFile saves the board to the given file. The file is replaced
+// atomically, so a failure while saving never leaves a truncated board behind.
 func BoardToFile(filename string, b *Board) error {
-	f, err := os.Create(filename)
+	return BoardToFileWithBackups(filename, b, 0)
+}
+
+// BoardToFileWithBackups saves the board to the given file, keeping up to
+// backups timestamped copies of the previous versions of the file.
+func BoardToFileWithBackups(filename string, b *Board, backups int) error {
+	lock, err := LockBoard(filename, true)
 	if err != nil {
+		return fmt.Errorf("unable to lock %s: %v", filename, err)
+	}
+	defer lock.Unlock()
+
+	if err := BackupFile(filename, backups); err != nil {
 		return err
 	}
-	defer f.Close()
 
-	enc := yaml.NewEncoder(f)
-	return enc.Encode(b)
+	b.Version = SchemaVersion
+	return writeFileAtomic(filename, func(w io.Writer) error {
+		enc := yaml.NewEncoder(w)
+		if err := enc.Encode(b); err != nil {
+			return err
+		}
+		return enc.Close()
+	})
+}
+
+// remarshal converts src into dst by encoding it into yaml and decoding back.
+func remarshal(src, dst interface{}) error {
+	data, err := yaml.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(data, dst)
 }
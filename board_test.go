@@ -0,0 +1,159 @@
+package nonota
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEnsureIDs(t *testing.T) {
+	tt := &TaskTime{ID: "entry"}
+	b := &Board{Lists: []*List{{
+		Tasks: []*Task{{ID: "task", Times: []*TaskTime{tt, {}}}, {}},
+	}}}
+	if !b.ensureIDs() {
+		t.Fatalf("expected ids to be assigned")
+	}
+
+	// Existing ids are kept and new ones are unique.
+	task := b.Lists[0].Tasks[0]
+	if task.ID != "task" || tt.ID != "entry" {
+		t.Fatalf("existing ids changed to %s and %s", task.ID, tt.ID)
+	}
+	ids := map[string]bool{}
+	for _, id := range []string{b.ID, b.Lists[0].ID, task.ID,
+		b.Lists[0].Tasks[1].ID, tt.ID, task.Times[1].ID} {
+		if id == "" || ids[id] {
+			t.Fatalf("missing or duplicated id %q", id)
+		}
+		ids[id] = true
+	}
+
+	if b.ensureIDs() {
+		t.Fatalf("ids assigned to a board that already had them")
+	}
+}
+
+func TestStableIDs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nonota")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fname := filepath.Join(dir, "board.yml")
+
+	// Entities added by hand (without ids) get them on load, which are
+	// then kept across saves.
+	b := &Board{
+		Lists: []*List{{Title: "List", Tasks: []*Task{{
+			Title: "Task",
+			Times: []*TaskTime{{Duration: time.Hour}},
+		}}}},
+	}
+	if err := BoardToFile(fname, b); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := BoardFromFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := loaded.Lists[0]
+	task := list.Tasks[0]
+	if loaded.ID == "" || list.ID == "" || task.ID == "" ||
+		task.Times[0].ID == "" {
+		t.Fatalf("ids not assigned on load")
+	}
+
+	// New entities get ids when created.
+	loaded.AppendNewTask(list)
+	newTask := list.Tasks[len(list.Tasks)-1]
+	newTT := &TaskTime{Duration: time.Minute}
+	newTask.AddTaskTime(newTT)
+	loaded.AppendNewList()
+	newList := loaded.Lists[len(loaded.Lists)-1]
+	if newTask.ID == "" || newTT.ID == "" || newList.ID == "" {
+		t.Fatalf("ids not assigned to new entities")
+	}
+
+	if err := BoardToFile(fname, loaded); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := BoardFromFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.ID != loaded.ID || reloaded.ListByID(newList.ID) == nil {
+		t.Fatalf("board or list ids changed after reloading")
+	}
+	for _, want := range []*Task{task, newTask} {
+		got := reloaded.TaskByID(want.ID)
+		if got == nil || got.Title != want.Title || len(got.Times) != 1 ||
+			got.Times[0].ID != want.Times[0].ID {
+			t.Fatalf("task %s not found after reloading", want.ID)
+		}
+	}
+}
+
+func TestDeleteArchiveRestore(t *testing.T) {
+	day := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	from, to := StartOfDay(day), EndOfDay(day)
+	b := &Board{ID: NewID()}
+	b.AppendNewList()
+	b.AppendNewList()
+	l1, l2 := b.Lists[0], b.Lists[1]
+	b.AppendNewTask(l1)
+	b.AppendNewTask(l1)
+	b.AppendNewTask(l2)
+	t1, t2, t3 := l1.Tasks[0], l1.Tasks[1], l2.Tasks[0]
+	for i, task := range []*Task{t1, t2, t3} {
+		start := day.Add(time.Duration(10+i) * time.Hour)
+		task.AddTaskTime(&TaskTime{
+			Start:    start,
+			End:      start.Add(time.Hour),
+			Duration: time.Hour,
+		})
+	}
+
+	// Archived items keep counting towards the totals.
+	b.ArchiveTask(t1)
+	b.ArchiveList(l2)
+	if !t1.Archived || !l2.Archived || t3.Archived {
+		t.Fatalf("unexpected archived flags")
+	}
+	if total := b.TotalTime(from, to); total != 3*time.Hour {
+		t.Fatalf("unexpected total with archived items %s", total)
+	}
+
+	// Restoring a task of an archived list also restores the list.
+	b.RestoreTask(t3)
+	if t3.Archived || l2.Archived {
+		t.Fatalf("task or list not restored")
+	}
+	b.ArchiveList(l2)
+	b.RestoreList(l2)
+	if l2.Archived || t3.Archived {
+		t.Fatalf("list not restored")
+	}
+
+	// Deleted items don't count anymore.
+	b.DeleteTask(t2)
+	if b.TaskByID(t2.ID) != nil || len(l1.Tasks) != 1 {
+		t.Fatalf("task not deleted")
+	}
+	b.DeleteList(l2)
+	if b.ListByID(l2.ID) != nil || b.TaskByID(t3.ID) != nil {
+		t.Fatalf("list not deleted")
+	}
+	if total := b.TotalTime(from, to); total != time.Hour {
+		t.Fatalf("unexpected total after deleting %s", total)
+	}
+
+	// Deleting items not in the board does nothing.
+	b.DeleteTask(&Task{ID: NewID()})
+	b.DeleteList(&List{ID: NewID()})
+	if len(b.Lists) != 1 || len(l1.Tasks) != 1 {
+		t.Fatalf("deleting unknown items changed the board")
+	}
+}
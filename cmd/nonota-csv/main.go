@@ -1,16 +1,35 @@
 package main
 
 import (
-	"github.com/matheusd/nonota"
+	"encoding/csv"
+	"fmt"
+	"io"
 	"os"
+	"strings"
 	"time"
-	"fmt"
+
+	"github.com/matheusd/nonota"
+	"github.com/matheusd/nonota/report"
+	_ "github.com/matheusd/nonota/sqlite"
 
 	flags "github.com/jessevdk/go-flags"
 )
 
+const dateFormat = "2006-01-02"
+
 type opts struct {
-	Current bool `long:"current" description:"Generate for the current month"`
+	Filename  string  `short:"f" long:"filename" description:"Filename of the board to use"`
+	Date      string  `long:"date" description:"Date within the billing period to export (in the YYYY-MM-DD or YYYY-MM format)"`
+	Current   bool    `long:"current" description:"Generate for the current billing period"`
+	From      string  `long:"from" description:"Export the times since this date (YYYY-MM-DD) instead of a billing period"`
+	To        string  `long:"to" description:"Export the times up to this date (YYYY-MM-DD, inclusive); defaults to today when --from is used"`
+	Columns   string  `long:"columns" description:"Comma-separated columns to export: list, task, description, tags, notes, hours, days (one column per day) and cost" default:"task,hours"`
+	Rate      float64 `long:"rate" description:"Rate per hour used for the cost column"`
+	Header    bool    `long:"header" description:"Output a header row with the column names"`
+	Footer    bool    `long:"footer" description:"Output a footer row with the totals"`
+	Tag       string  `long:"tag" description:"Only export tasks with the given tag"`
+	TZ        string  `long:"tz" description:"Time zone used to compute the billing period (overrides the board setting)"`
+	SkipCheck bool    `long:"skip-check" description:"Export even if the board has inconsistencies (see nonota check)"`
 }
 
 func getCmdOpts() *opts {
@@ -29,49 +48,231 @@ func getCmdOpts() *opts {
 	return cmdOpts
 }
 
+// validColumns are the columns that may be exported.
+var validColumns = map[string]bool{
+	"list":        true,
+	"task":        true,
+	"description": true,
+	"tags":        true,
+	"notes":       true,
+	"hours":       true,
+	"days":        true,
+	"cost":        true,
+}
+
+// parseColumns parses the --columns option.
+func parseColumns(opts *opts) ([]string, error) {
+	var columns []string
+	for _, c := range strings.Split(opts.Columns, ",") {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if c == "" {
+			continue
+		}
+		if !validColumns[c] {
+			return nil, fmt.Errorf("unknown column %q", c)
+		}
+		if c == "cost" && opts.Rate <= 0 {
+			return nil, fmt.Errorf("specify the --rate to export the cost")
+		}
+		columns = append(columns, c)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns to export")
+	}
+	return columns, nil
+}
+
+// exportRange returns the range of times to export, which is either the
+// billing period of the export (selected by --date and --current) or the days
+// between --from and --to.
+func exportRange(exp *report.Export, opts *opts) (time.Time, time.Time, error) {
+	loc := exp.Location
+	now := time.Now().In(loc)
+	if opts.From == "" && opts.To != "" {
+		return now, now, fmt.Errorf("--to requires --from")
+	}
+	if opts.From != "" && (opts.Date != "" || opts.Current) {
+		return now, now, fmt.Errorf("--from can't be used with --date or --current")
+	}
+	if opts.From != "" {
+		from, err := time.ParseInLocation(dateFormat, opts.From, loc)
+		if err != nil {
+			return now, now, fmt.Errorf("invalid date %q", opts.From)
+		}
+		to := now
+		if opts.To != "" {
+			to, err = time.ParseInLocation(dateFormat, opts.To, loc)
+			if err != nil {
+				return now, now, fmt.Errorf("invalid date %q", opts.To)
+			}
+		}
+		if to.Before(from) {
+			return now, now, fmt.Errorf("--to is before --from")
+		}
+		return nonota.StartOfDay(from), nonota.EndOfDay(to), nil
+	}
+	return exp.From, exp.To, nil
+}
+
+// days returns the start of every day between the given times.
+func days(start, end time.Time) []time.Time {
+	var res []time.Time
+	for d := nonota.StartOfDay(start); !d.After(end); d = d.AddDate(0, 0, 1) {
+		res = append(res, d)
+	}
+	return res
+}
+
+func hours(d time.Duration) string {
+	return fmt.Sprintf("%.2f", d.Hours())
+}
+
+// writeCSV writes a row for every task of the report (grouped by list, task
+// and day) with the given columns, along with the header and footer rows if
+// requested by the options.
+func writeCSV(out io.Writer, rep *report.Report, columns []string, exportDays []time.Time, opts *opts) error {
+	w := csv.NewWriter(out)
+	if opts.Header {
+		var header []string
+		for _, c := range columns {
+			if c == "days" {
+				for _, d := range exportDays {
+					header = append(header, d.Format(dateFormat))
+				}
+				continue
+			}
+			header = append(header, c)
+		}
+		w.Write(header)
+	}
+
+	// Output a row for every task with time in the range.
+	totTime := rep.Root.Duration
+	dayTotals := make([]time.Duration, len(exportDays))
+	rep.Root.Walk(func(path []*report.Node) {
+		if len(path) != 2 {
+			return
+		}
+		l, t := path[0].List, path[1].Task
+		taskTime := path[1].Duration
+
+		var record []string
+		for _, c := range columns {
+			switch c {
+			case "list":
+				record = append(record, l.Title)
+			case "task":
+				record = append(record, t.Title)
+			case "description":
+				record = append(record, t.Description)
+			case "tags":
+				record = append(record, strings.Join(t.Tags, " "))
+			case "notes":
+				record = append(record, strings.Join(path[1].Notes(), "; "))
+			case "hours":
+				record = append(record, hours(taskTime))
+			case "days":
+				for i, d := range exportDays {
+					var dayTime time.Duration
+					if n := path[1].Child(report.DayKey(d)); n != nil {
+						dayTime = n.Duration
+					}
+					dayTotals[i] += dayTime
+					record = append(record, hours(dayTime))
+				}
+			case "cost":
+				record = append(record, fmt.Sprintf("%.2f", taskTime.Hours()*opts.Rate))
+			}
+		}
+		w.Write(record)
+	})
+
+	if opts.Footer {
+		var footer []string
+		label := "Total"
+		for _, c := range columns {
+			switch c {
+			case "hours":
+				footer = append(footer, hours(totTime))
+			case "days":
+				for _, dayTime := range dayTotals {
+					footer = append(footer, hours(dayTime))
+				}
+			case "cost":
+				footer = append(footer, fmt.Sprintf("%.2f", totTime.Hours()*opts.Rate))
+			default:
+				// The label goes in the first text column.
+				footer = append(footer, label)
+				label = ""
+			}
+		}
+		w.Write(footer)
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
 func main() {
 	opts := getCmdOpts()
 
-	ref := time.Now()
-	if !opts.Current {
-		// Go back a day prior to the start of the current period
-		// to get a date in the previous billing period
-		ref = nonota.StartOfBilling(nonota.StartOfBilling(ref).Add(time.Hour*-24))
-	}
-	start := nonota.StartOfBilling(ref)
-	end := nonota.EndOfBilling(ref)
 	dtFormat := "2006-01-02 15:04:05"
 
-	filename := "nonota-board.yml"
-	board, err := nonota.BoardFromFile(filename)
+	columns, err := parseColumns(opts)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 
-	tasks := make([]*nonota.Task, 0)
-	for _, l := range board.Lists {
-		for _, t := range l.Tasks {
-			taskTime := t.TotalTime(start, end)
-			if taskTime <= 0 {
-				continue
-			}
+	exp, err := report.LoadExport(report.ExportOptions{
+		Filename: opts.Filename,
+		Date:     opts.Date,
+		Current:  opts.Current,
+		TZ:       opts.TZ,
+	})
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if exp.From, exp.To, err = exportRange(exp, opts); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	board, start, end := exp.Board, exp.From, exp.To
 
-			tasks = append(tasks, t)
+	if !opts.SkipCheck {
+		if err := exp.Check(os.Stderr); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
 		}
 	}
+	exportDays := days(start, end)
 
-	var totTime time.Duration
-	// Collected all relevant tasks. Output csv.
-	for _, t := range tasks {
-		csvFmt := "\"%s\",%.2f\n"
-		taskTime := t.TotalTime(start, end)
-		fmt.Printf(csvFmt, t.Title, taskTime.Hours())
-		totTime += taskTime
+	groups := []report.GroupBy{report.GroupList, report.GroupTask, report.GroupDay}
+	rep := report.New(board, report.Options{
+		From:   start,
+		To:     end,
+		Groups: groups,
+		Tag:    opts.Tag,
+	})
+
+	totTime := rep.Root.Duration
+	if err := writeCSV(os.Stdout, rep, columns, exportDays, opts); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
 	}
 
 	fmt.Fprintf(os.Stderr, "\nGenerated CSV between %s and %s\n",
 		start.Format(dtFormat), end.Format(dtFormat))
 	fmt.Fprintf(os.Stderr, "Total computed time: %s (%.2f hours)\n", totTime,
 		totTime.Hours())
-}
\ No newline at end of file
+
+	fmt.Fprintf(os.Stderr, "\nTotal time per tag:\n")
+	byTag := report.New(board, report.Options{
+		From:   start,
+		To:     end,
+		Groups: []report.GroupBy{report.GroupTag},
+		Tag:    opts.Tag,
+	})
+	report.TextRenderer{}.Render(os.Stderr, byTag)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matheusd/nonota"
+	"github.com/matheusd/nonota/report"
+)
+
+func TestParseColumns(t *testing.T) {
+	columns, err := parseColumns(&opts{Columns: " List,task, ,DAYS,cost", Rate: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(columns, []string{"list", "task", "days", "cost"}) {
+		t.Fatalf("unexpected columns %v", columns)
+	}
+
+	bad := []*opts{
+		{Columns: "task,unknown"},
+		{Columns: "task,cost"},
+		{Columns: " , "},
+	}
+	for _, o := range bad {
+		if _, err := parseColumns(o); err == nil {
+			t.Fatalf("expected error parsing columns %q", o.Columns)
+		}
+	}
+}
+
+func TestExportRange(t *testing.T) {
+	loc := time.FixedZone("", -3*60*60)
+	exp := &report.Export{
+		Location: loc,
+		From:     time.Date(2019, 2, 1, 0, 0, 0, 0, loc),
+		To:       time.Date(2019, 2, 28, 23, 59, 59, 0, loc),
+	}
+
+	// Without --from, the billing period of the export is used.
+	from, to, err := exportRange(exp, &opts{})
+	if err != nil || !from.Equal(exp.From) || !to.Equal(exp.To) {
+		t.Fatalf("unexpected billing range %s - %s (%v)", from, to, err)
+	}
+
+	// --from and --to include whole days in the export location.
+	from, to, err = exportRange(exp, &opts{From: "2019-03-01", To: "2019-03-07"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !from.Equal(time.Date(2019, 3, 1, 0, 0, 0, 0, loc)) ||
+		!to.Equal(time.Date(2019, 3, 7, 23, 59, 59, 0, loc)) {
+		t.Fatalf("unexpected range %s - %s", from, to)
+	}
+	if n := len(days(from, to)); n != 7 {
+		t.Fatalf("unexpected number of days %d", n)
+	}
+
+	// --to defaults to today.
+	from, to, err = exportRange(exp, &opts{From: "2019-03-01"})
+	if err != nil || !to.After(from) || !to.Equal(nonota.EndOfDay(time.Now().In(loc))) {
+		t.Fatalf("unexpected open range %s - %s (%v)", from, to, err)
+	}
+
+	bad := []*opts{
+		{To: "2019-03-07"},
+		{From: "2019-03"},
+		{From: "2019-03-01", To: "tomorrow"},
+		{From: "2019-03-07", To: "2019-03-01"},
+		{From: "2019-03-01", Date: "2019-02"},
+		{From: "2019-03-01", To: "2019-03-07", Current: true},
+	}
+	for _, o := range bad {
+		if _, _, err := exportRange(exp, o); err == nil {
+			t.Fatalf("expected error for range %q - %q (date %q, current %v)",
+				o.From, o.To, o.Date, o.Current)
+		}
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	day := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	entry := func(start, d time.Duration, note string) *nonota.TaskTime {
+		return &nonota.TaskTime{
+			ID:       nonota.NewID(),
+			Start:    day.Add(start),
+			End:      day.Add(start + d),
+			Duration: d,
+			Note:     note,
+		}
+	}
+	b := &nonota.Board{ID: nonota.NewID(), Lists: []*nonota.List{{
+		ID:    nonota.NewID(),
+		Title: "List",
+		Tasks: []*nonota.Task{{
+			ID:    nonota.NewID(),
+			Title: "First #a",
+			Tags:  []string{"a"},
+			Times: []*nonota.TaskTime{
+				entry(10*time.Hour, time.Hour, "one"),
+				entry(34*time.Hour, 30*time.Minute, "two"),
+			},
+		}, {
+			ID:    nonota.NewID(),
+			Title: "Second",
+			Times: []*nonota.TaskTime{entry(12*time.Hour, 2*time.Hour, "")},
+		}, {
+			ID:    nonota.NewID(),
+			Title: "Without time",
+		}},
+	}}}
+	from, to := day, nonota.EndOfDay(day.AddDate(0, 0, 1))
+	rep := report.New(b, report.Options{
+		From:   from,
+		To:     to,
+		Groups: []report.GroupBy{report.GroupList, report.GroupTask, report.GroupDay},
+	})
+
+	o := &opts{Rate: 10, Header: true, Footer: true}
+	columns := []string{"list", "task", "tags", "notes", "days", "hours", "cost"}
+	var buf bytes.Buffer
+	if err := writeCSV(&buf, rep, columns, days(from, to), o); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"list,task,tags,notes,2019-03-01,2019-03-02,hours,cost",
+		"List,First #a,a,one; two,1.00,0.50,1.50,15.00",
+		"List,Second,,,2.00,0.00,2.00,20.00",
+		// The label goes in the first text column of the footer.
+		"Total,,,,3.00,0.50,3.50,35.00",
+		"",
+	}, "\n")
+	if buf.String() != want {
+		t.Fatalf("unexpected csv:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	// Without header and footer, only the task rows are written.
+	buf.Reset()
+	err := writeCSV(&buf, rep, []string{"hours", "task"}, nil, &opts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "1.50,First #a\n2.00,Second\n" {
+		t.Fatalf("unexpected csv without header and footer:\n%s", buf.String())
+	}
+}
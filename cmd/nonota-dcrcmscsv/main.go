@@ -3,23 +3,27 @@ package main
 import (
 	"fmt"
 	"os"
-	"regexp"
 	"strings"
 	"time"
 
 	"github.com/matheusd/nonota"
+	"github.com/matheusd/nonota/report"
+	_ "github.com/matheusd/nonota/sqlite"
 
 	flags "github.com/jessevdk/go-flags"
 )
 
 type opts struct {
-	Filename string  `short:"f" long:"filename" description:"Filename of the board to use"`
-	Date     string  `long:"date" description:"Reference date to generate the billing"`
-	Current  bool    `long:"current" description:"Generate for the current month"`
-	Rate     float64 `long:"rate" description:"Contractor rate in USD/hour"`
-	Domain   string  `long:"domain" description:"Default domain for expenses"`
-	Name     string  `long:"name" description:"Name to use on header"`
-	Location string  `long:"location" description:"Location to use on header"`
+	Filename  string  `short:"f" long:"filename" description:"Filename of the board to use"`
+	Date      string  `long:"date" description:"Date within the billing period to export (in the YYYY-MM-DD or YYYY-MM format)"`
+	Current   bool    `long:"current" description:"Generate for the current billing period"`
+	Rate      float64 `long:"rate" description:"Contractor rate in USD/hour"`
+	Domain    string  `long:"domain" description:"Default domain for expenses"`
+	Name      string  `long:"name" description:"Name to use on header"`
+	Location  string  `long:"location" description:"Location to use on header"`
+	Tag       string  `long:"tag" description:"Only bill tasks with the given tag"`
+	TZ        string  `long:"tz" description:"Time zone used to compute the billing period (overrides the board setting)"`
+	SkipCheck bool    `long:"skip-check" description:"Export even if the board has inconsistencies (see nonota check)"`
 }
 
 func getCmdOpts() *opts {
@@ -38,25 +42,14 @@ func getCmdOpts() *opts {
 	return cmdOpts
 }
 
-var reExtractSub *regexp.Regexp
-
-func init() {
-	var err error
-	reExtractSub, err = regexp.Compile("#(\\S*)\\s?")
-	if err != nil {
-		panic(fmt.Errorf("error compiling regexp: %s", err))
-	}
-}
-
-func extractSubdomain(s string) string {
-	match := reExtractSub.FindAllStringSubmatch(s, -1)
-	if len(match) < 1 {
+// taskSubdomain returns the billing subdomain of the given task, which is the
+// first of its tags. Tags are only parsed from titles when preceded by a space
+// (see nonota.ParseTags), so a "#" within a word doesn't set the subdomain.
+func taskSubdomain(t *nonota.Task) string {
+	if len(t.Tags) == 0 {
 		return ""
 	}
-	if len(match[0]) < 2 {
-		return ""
-	}
-	return match[0][1]
+	return t.Tags[0]
 }
 
 func quote(s string) string {
@@ -78,45 +71,34 @@ func main() {
 		os.Exit(1)
 	}
 
-	ref := time.Now()
-	if opts.Date != "" {
-		var err error
-		ref, err = time.Parse("2006-01-02", opts.Date)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-	} else if !opts.Current {
-		// Go back a day prior to the start of the current period
-		// to get a date in the previous billing period
-		ref = nonota.StartOfBilling(nonota.StartOfBilling(ref).Add(time.Hour * -24))
-	}
-	start := nonota.StartOfBilling(ref)
-	end := nonota.EndOfBilling(ref)
 	dtFormat := "2006-01-02 15:04:05"
 
-	filename := "nonota-board.yml"
-	if opts.Filename != "" {
-		filename = opts.Filename
-	}
-	board, err := nonota.BoardFromFile(filename)
+	exp, err := report.LoadExport(report.ExportOptions{
+		Filename: opts.Filename,
+		Date:     opts.Date,
+		Current:  opts.Current,
+		TZ:       opts.TZ,
+	})
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+	board, start, end := exp.Board, exp.From, exp.To
 
-	tasks := make([]*nonota.Task, 0)
-	for _, l := range board.Lists {
-		for _, t := range l.Tasks {
-			taskTime := t.TotalTime(start, end)
-			if taskTime <= 0 {
-				continue
-			}
-
-			tasks = append(tasks, t)
+	if !opts.SkipCheck {
+		if err := exp.Check(os.Stderr); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
 		}
 	}
 
+	rep := report.New(board, report.Options{
+		From:   start,
+		To:     end,
+		Groups: []report.GroupBy{report.GroupTask},
+		Tag:    opts.Tag,
+	})
+
 	var totTime time.Duration
 	var totExpense float64
 
@@ -129,18 +111,19 @@ func main() {
 	fmt.Printf("PaymentAddr,\n")
 	fmt.Printf("\n")
 
-	// Collected all relevant tasks. Output csv.
-	for _, t := range tasks {
+	// Output a line for every task with time in the billing period.
+	for _, n := range rep.Root.Children {
+		t := n.Task
 		// TODO: Extract domain from task list
 		typ := "labor"
 		domain := opts.Domain
-		subdomain := extractSubdomain(t.Title)
+		subdomain := taskSubdomain(t)
 		descr := quote(t.Title)
 		if t.Description != "" {
 			descr += "\\n\\n" + quote(t.Description)
 		}
 		token := ""
-		taskTime := t.TotalTime(start, end)
+		taskTime := n.Duration
 		labor := taskTime.Hours()
 		expense := labor * opts.Rate
 
@@ -157,4 +140,19 @@ func main() {
 	fmt.Fprintf(os.Stderr, "Total computed time: %s (%.2f hours)\n", totTime,
 		totTime.Hours())
 	fmt.Fprintf(os.Stderr, "Total Expense: $ %.2f\n", totExpense)
+	printTagTotals(board, start, end)
+}
+
+// printTagTotals prints the total time per tag between the given times.
+func printTagTotals(board *nonota.Board, start, end time.Time) {
+	rep := report.New(board, report.Options{
+		From:   start,
+		To:     end,
+		Groups: []report.GroupBy{report.GroupTag},
+	})
+	if len(rep.Root.Children) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\nTotal time per tag:\n")
+	report.TextRenderer{}.Render(os.Stderr, rep)
 }
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/matheusd/nonota"
+)
+
+func TestTaskSubdomain(t *testing.T) {
+	tests := []struct {
+		task *nonota.Task
+		want string
+	}{
+		{&nonota.Task{Title: "No tags"}, ""},
+		{&nonota.Task{Title: "Fix #ui and #docs", Tags: []string{"ui", "docs"}}, "ui"},
+
+		// Tags added with the tag field or whose #tag was removed from
+		// the title are used as well.
+		{&nonota.Task{Title: "Imported", Tags: []string{"imported", "ui"}}, "imported"},
+
+		// A # within a word is not a tag.
+		{&nonota.Task{Title: "foo#bar", Tags: nonota.ParseTags("foo#bar")}, ""},
+	}
+	for _, tc := range tests {
+		if got := taskSubdomain(tc.task); got != tc.want {
+			t.Errorf("taskSubdomain(%q) = %q, want %q", tc.task.Title, got,
+				tc.want)
+		}
+	}
+}
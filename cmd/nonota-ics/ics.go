@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/matheusd/nonota"
+)
+
+const icsTimeFormat = "20060102T150405Z"
+
+// icsEscape escapes text to be used in a TEXT value (RFC 5545, 3.3.11).
+func icsEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, ";", `\;`)
+	s = strings.ReplaceAll(s, ",", `\,`)
+	s = strings.ReplaceAll(s, "\r\n", `\n`)
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
+
+// icsDuration formats a duration as a DURATION value (RFC 5545, 3.3.6).
+func icsDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	h := d / time.Hour
+	m := (d % time.Hour) / time.Minute
+	s := (d % time.Minute) / time.Second
+	return fmt.Sprintf("PT%dH%dM%dS", h, m, s)
+}
+
+// icsWriter writes content lines, folded at 75 octets and terminated by
+// CRLF as required by RFC 5545.
+type icsWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (iw *icsWriter) line(name, value string) {
+	if iw.err != nil {
+		return
+	}
+	s := name + ":" + value
+
+	// The space that starts continuation lines counts towards their 75
+	// octets.
+	max := 75
+	for len(s) > max {
+		// Never split a multi-byte character.
+		i := max
+		for i > 0 && !utf8.RuneStart(s[i]) {
+			i--
+		}
+		iw.w.WriteString(s[:i] + "\r\n ")
+		s = s[i:]
+		max = 74
+	}
+	_, iw.err = iw.w.WriteString(s + "\r\n")
+}
+
+// event is a time entry to be exported along with its task.
+type event struct {
+	task *nonota.Task
+	tt   *nonota.TaskTime
+}
+
+// writeCalendar writes the events as an iCalendar file. The UID of every event
+// is derived from the id of its time entry, so calendar applications update
+// the events when a calendar is exported again.
+func writeCalendar(w io.Writer, name string, events []event, now time.Time) error {
+	iw := &icsWriter{w: bufio.NewWriter(w)}
+	iw.line("BEGIN", "VCALENDAR")
+	iw.line("VERSION", "2.0")
+	iw.line("PRODID", "-//nonota//nonota-ics//EN")
+	iw.line("CALSCALE", "GREGORIAN")
+	if name != "" {
+		iw.line("X-WR-CALNAME", icsEscape(name))
+	}
+
+	stamp := now.UTC().Format(icsTimeFormat)
+	for _, e := range events {
+		tt, t := e.tt, e.task
+		iw.line("BEGIN", "VEVENT")
+		iw.line("UID", tt.ID+"@nonota")
+		iw.line("DTSTAMP", stamp)
+		iw.line("DTSTART", tt.Start.UTC().Format(icsTimeFormat))
+		if tt.End.After(tt.Start) {
+			iw.line("DTEND", tt.End.UTC().Format(icsTimeFormat))
+		} else {
+			// Entries without a timeframe are shown as taking
+			// their duration.
+			iw.line("DURATION", icsDuration(tt.Duration))
+		}
+		iw.line("SUMMARY", icsEscape(t.Title))
+
+		var descr []string
+		if tt.Note != "" {
+			descr = append(descr, tt.Note)
+		}
+		if t.Description != "" {
+			descr = append(descr, t.Description)
+		}
+		descr = append(descr, fmt.Sprintf("Recorded: %s", tt.Duration.Round(time.Second)))
+		iw.line("DESCRIPTION", icsEscape(strings.Join(descr, "\n\n")))
+		if len(t.Tags) > 0 {
+			tags := make([]string, len(t.Tags))
+			for i, tag := range t.Tags {
+				tags[i] = icsEscape(tag)
+			}
+			iw.line("CATEGORIES", strings.Join(tags, ","))
+		}
+		iw.line("END", "VEVENT")
+	}
+
+	iw.line("END", "VCALENDAR")
+	if iw.err != nil {
+		return iw.err
+	}
+	return iw.w.Flush()
+}
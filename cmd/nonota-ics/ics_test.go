@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/matheusd/nonota"
+)
+
+func TestICSEscape(t *testing.T) {
+	tests := []struct {
+		in, out string
+	}{
+		{"plain", "plain"},
+		{`a\b`, `a\\b`},
+		{"a;b,c", `a\;b\,c`},
+		{"a\r\nb\nc", `a\nb\nc`},
+	}
+	for _, tc := range tests {
+		if out := icsEscape(tc.in); out != tc.out {
+			t.Errorf("icsEscape(%q) = %q, want %q", tc.in, out, tc.out)
+		}
+	}
+}
+
+func TestICSFolding(t *testing.T) {
+	tests := []string{
+		"short",
+		strings.Repeat("a", 200),
+		strings.Repeat("é", 100),
+		strings.Repeat("a", 72) + "日本語のテキスト" + strings.Repeat("b", 80),
+	}
+	for _, value := range tests {
+		var buf bytes.Buffer
+		iw := &icsWriter{w: bufio.NewWriter(&buf)}
+		iw.line("DESCRIPTION", value)
+		if err := iw.w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+
+		out := buf.String()
+		if !strings.HasSuffix(out, "\r\n") {
+			t.Fatalf("line not terminated by CRLF: %q", out)
+		}
+		lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+		var unfolded string
+		for i, l := range lines {
+			if len(l) > 75 {
+				t.Fatalf("line %d has %d octets: %q", i, len(l), l)
+			}
+			if !utf8.ValidString(l) {
+				t.Fatalf("line %d splits a character: %q", i, l)
+			}
+			if i > 0 {
+				if !strings.HasPrefix(l, " ") {
+					t.Fatalf("continuation line %d doesn't start with "+
+						"a space: %q", i, l)
+				}
+				l = l[1:]
+			}
+			unfolded += l
+		}
+		if unfolded != "DESCRIPTION:"+value {
+			t.Fatalf("unexpected unfolded line %q", unfolded)
+		}
+	}
+}
+
+func TestWriteCalendarUIDs(t *testing.T) {
+	start := time.Date(2019, 3, 1, 10, 0, 0, 0, time.FixedZone("", -3*60*60))
+	task := &nonota.Task{ID: nonota.NewID(), Title: "Task, with; chars"}
+	tt := &nonota.TaskTime{
+		ID:       nonota.NewID(),
+		Start:    start,
+		End:      start.Add(time.Hour),
+		Duration: time.Hour,
+	}
+	task.Times = []*nonota.TaskTime{tt}
+
+	export := func(now time.Time) string {
+		var buf bytes.Buffer
+		err := writeCalendar(&buf, "test", []event{{task, tt}}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+	first := export(start.Add(2 * time.Hour))
+	for _, want := range []string{
+		"UID:" + tt.ID + "@nonota\r\n",
+		"DTSTART:20190301T130000Z\r\n",
+		"DTEND:20190301T140000Z\r\n",
+		`SUMMARY:Task\, with\; chars` + "\r\n",
+	} {
+		if !strings.Contains(first, want) {
+			t.Fatalf("missing %q in calendar:\n%s", want, first)
+		}
+	}
+
+	// Exporting again (even after editing the entry) keeps the UID, so
+	// calendar applications update the event.
+	tt.Note = "edited"
+	second := export(start.Add(3 * time.Hour))
+	if !strings.Contains(second, "UID:"+tt.ID+"@nonota\r\n") {
+		t.Fatalf("UID changed in second export:\n%s", second)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/matheusd/nonota/report"
+	_ "github.com/matheusd/nonota/sqlite"
+
+	flags "github.com/jessevdk/go-flags"
+)
+
+type opts struct {
+	Filename  string `short:"f" long:"filename" description:"Filename of the board to use"`
+	Date      string `long:"date" description:"Date within the billing period to export (in the YYYY-MM-DD or YYYY-MM format)"`
+	Current   bool   `long:"current" description:"Export the current billing period"`
+	Name      string `long:"name" description:"Name of the calendar" default:"nonota"`
+	Tag       string `long:"tag" description:"Only export tasks with the given tag"`
+	TZ        string `long:"tz" description:"Time zone used to compute the billing period (overrides the board setting)"`
+	SkipCheck bool   `long:"skip-check" description:"Export even if the board has inconsistencies (see nonota check)"`
+}
+
+func getCmdOpts() *opts {
+	cmdOpts := &opts{}
+	parser := flags.NewParser(cmdOpts, flags.Default)
+	_, err := parser.Parse()
+	if err != nil {
+		e, ok := err.(*flags.Error)
+		if ok && e.Type == flags.ErrHelp {
+			os.Exit(0)
+		}
+		fmt.Printf("Argument error: %v\n", e)
+		os.Exit(1)
+	}
+
+	return cmdOpts
+}
+
+func main() {
+	opts := getCmdOpts()
+
+	dtFormat := "2006-01-02 15:04:05"
+
+	exp, err := report.LoadExport(report.ExportOptions{
+		Filename: opts.Filename,
+		Date:     opts.Date,
+		Current:  opts.Current,
+		TZ:       opts.TZ,
+	})
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	board, start, end := exp.Board, exp.From, exp.To
+
+	if !opts.SkipCheck {
+		if err := exp.Check(os.Stderr); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	var events []event
+	for _, l := range board.Lists {
+		for _, t := range l.Tasks {
+			if opts.Tag != "" && !t.HasTag(opts.Tag) {
+				continue
+			}
+			for _, tt := range t.Times {
+				if tt.DurationWithin(start, end) > 0 {
+					events = append(events, event{t, tt})
+				}
+			}
+		}
+	}
+
+	if err := writeCalendar(os.Stdout, opts.Name, events, time.Now()); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	fmt.Fprintf(os.Stderr, "\nExported %d time entries between %s and %s\n",
+		len(events), start.Format(dtFormat), end.Format(dtFormat))
+}
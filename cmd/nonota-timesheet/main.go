@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/matheusd/nonota/report"
+	_ "github.com/matheusd/nonota/sqlite"
+
+	flags "github.com/jessevdk/go-flags"
+)
+
+type opts struct {
+	Filename  string `short:"f" long:"filename" description:"Filename of the board to use"`
+	Date      string `long:"date" description:"Date within the billing period to export (in the YYYY-MM-DD or YYYY-MM format)"`
+	Current   bool   `long:"current" description:"Generate for the current billing period"`
+	Format    string `long:"format" description:"Format of the timesheet (markdown or html)" default:"markdown"`
+	Title     string `long:"title" description:"Title of the timesheet" default:"Timesheet"`
+	Tag       string `long:"tag" description:"Only include tasks with the given tag"`
+	TZ        string `long:"tz" description:"Time zone used to compute the billing period (overrides the board setting)"`
+	SkipCheck bool   `long:"skip-check" description:"Export even if the board has inconsistencies (see nonota check)"`
+}
+
+func getCmdOpts() *opts {
+	cmdOpts := &opts{}
+	parser := flags.NewParser(cmdOpts, flags.Default)
+	_, err := parser.Parse()
+	if err != nil {
+		e, ok := err.(*flags.Error)
+		if ok && e.Type == flags.ErrHelp {
+			os.Exit(0)
+		}
+		fmt.Printf("Argument error: %v\n", e)
+		os.Exit(1)
+	}
+
+	return cmdOpts
+}
+
+func main() {
+	opts := getCmdOpts()
+
+	var renderer report.Renderer
+	switch opts.Format {
+	case "markdown", "md":
+		renderer = report.MarkdownRenderer{Title: opts.Title}
+	case "html":
+		renderer = report.HTMLRenderer{Title: opts.Title}
+	default:
+		fmt.Printf("Unknown format %q\n", opts.Format)
+		os.Exit(1)
+	}
+
+	dtFormat := "2006-01-02 15:04:05"
+
+	exp, err := report.LoadExport(report.ExportOptions{
+		Filename: opts.Filename,
+		Date:     opts.Date,
+		Current:  opts.Current,
+		TZ:       opts.TZ,
+	})
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	board, start, end := exp.Board, exp.From, exp.To
+
+	if !opts.SkipCheck {
+		if err := exp.Check(os.Stderr); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	rep := report.New(board, report.Options{
+		From:   start,
+		To:     end,
+		Groups: []report.GroupBy{report.GroupList, report.GroupTask},
+		Tag:    opts.Tag,
+	})
+	if err := renderer.Render(os.Stdout, rep); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	fmt.Fprintf(os.Stderr, "\nGenerated timesheet between %s and %s\n",
+		start.Format(dtFormat), end.Format(dtFormat))
+	fmt.Fprintf(os.Stderr, "Total computed time: %s (%.2f hours)\n",
+		rep.Root.Duration, rep.Root.Duration.Hours())
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/matheusd/nonota"
+)
+
+type checkCmd struct {
+	opts *opts
+
+	Fix bool `long:"fix" description:"Fix the issues that can be fixed automatically"`
+}
+
+// Execute reports the inconsistencies of the board, optionally fixing them.
+func (c *checkCmd) Execute(args []string) error {
+	filename := c.opts.Filename
+	if c.Fix {
+		// Fixing while the board is open would be pointless, as the
+		// running instance would overwrite it on its next save.
+		lock, err := nonota.LockInstance(filename)
+		if err == nonota.ErrLocked {
+			return fmt.Errorf("board %s is open in another nonota instance", filename)
+		}
+		if err != nil {
+			return err
+		}
+		defer lock.Unlock()
+	}
+
+	storage, err := nonota.OpenStorage(filename, nonota.StorageOptions{
+		Backups: c.opts.Backups,
+	})
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	board, err := storage.Load()
+	if err != nil {
+		return err
+	}
+
+	if c.Fix {
+		if changes := board.Fix(); changes > 0 {
+			if err := storage.Save(board); err != nil {
+				return err
+			}
+			fmt.Printf("Made %d changes to fix %s\n", changes, filename)
+		}
+	}
+
+	issues := board.Validate()
+	if len(issues) == 0 {
+		fmt.Printf("No issues found in %s\n", filename)
+		return nil
+	}
+	var errors int
+	for _, issue := range issues {
+		if issue.Kind.Warning() {
+			fmt.Printf("Warning: %s\n", issue)
+			continue
+		}
+		fmt.Println(issue)
+		errors++
+	}
+	if errors == 0 {
+		fmt.Printf("Found %d warnings in %s\n", len(issues), filename)
+		return nil
+	}
+	return fmt.Errorf("found %d issues in %s", errors, filename)
+}
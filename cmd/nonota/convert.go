@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/matheusd/nonota"
+)
+
+type convertCmd struct {
+	opts *opts
+
+	Force bool `long:"force" description:"Overwrite the destination board if it already exists"`
+
+	Args struct {
+		Destination string `positional-arg-name:"destination" description:"Filename of the new board (the extension selects its storage, such as .yml or .db)"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+// Execute copies the board into a new file, possibly using a different
+// storage backend.
+func (c *convertCmd) Execute(args []string) error {
+	src, dst := c.opts.Filename, c.Args.Destination
+	if _, err := os.Stat(src); err != nil {
+		return err
+	}
+	if _, err := os.Stat(dst); err == nil && !c.Force {
+		return fmt.Errorf("destination %s already exists", dst)
+	}
+
+	srcStorage, err := nonota.OpenStorage(src, nonota.StorageOptions{})
+	if err != nil {
+		return err
+	}
+	defer srcStorage.Close()
+
+	board, err := srcStorage.Load()
+	if err != nil {
+		return err
+	}
+
+	dstStorage, err := nonota.OpenStorage(dst, nonota.StorageOptions{
+		Backups: c.opts.Backups,
+	})
+	if err != nil {
+		return err
+	}
+	defer dstStorage.Close()
+
+	if err := dstStorage.Save(board); err != nil {
+		return err
+	}
+
+	fmt.Printf("Converted %s into %s\n", src, dst)
+	return nil
+}
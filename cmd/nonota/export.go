@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/matheusd/nonota"
+	yaml "gopkg.in/yaml.v2"
+)
+
+type exportCmd struct {
+	opts *opts
+
+	Format string `long:"format" description:"Format of the exported board (json or yaml)" default:"json"`
+	Output string `short:"o" long:"output" description:"File to write the board to (instead of the standard output)"`
+}
+
+// Execute writes the whole board in the given format.
+func (c *exportCmd) Execute(args []string) error {
+	var encode func(w io.Writer, b *nonota.Board) error
+	switch c.Format {
+	case "json":
+		encode = nonota.BoardToJSON
+	case "yaml":
+		encode = func(w io.Writer, b *nonota.Board) error {
+			return yaml.NewEncoder(w).Encode(b)
+		}
+	default:
+		return fmt.Errorf("unknown format %q", c.Format)
+	}
+
+	storage, err := nonota.OpenStorage(c.opts.Filename, nonota.StorageOptions{})
+	if err != nil {
+		return err
+	}
+	board, err := storage.Load()
+	storage.Close()
+	if err != nil {
+		return err
+	}
+
+	// The export is lossless, so issues are exported as they are, but the
+	// user is warned about them.
+	for _, issue := range board.Validate() {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", issue)
+	}
+
+	if c.Output == "" {
+		return encode(os.Stdout, board)
+	}
+	f, err := os.Create(c.Output)
+	if err != nil {
+		return err
+	}
+	if err := encode(f, board); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
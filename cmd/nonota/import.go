@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/matheusd/nonota"
+)
+
+type importCmd struct {
+	opts *opts
+
+	Force bool `long:"force" description:"Replace the board if it already exists"`
+
+	Args struct {
+		Source string `positional-arg-name:"source" description:"JSON file with the board to import (as written by nonota export)"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+// Execute replaces the board with the one in the given json file.
+func (c *importCmd) Execute(args []string) error {
+	filename := c.opts.Filename
+	if _, err := os.Stat(filename); err == nil && !c.Force {
+		return fmt.Errorf("board %s already exists (use --force to replace it)", filename)
+	}
+
+	f, err := os.Open(c.Args.Source)
+	if err != nil {
+		return err
+	}
+	board, err := nonota.BoardFromJSON(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	for _, issue := range board.Validate() {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", issue)
+	}
+
+	// Importing while the board is open would be pointless, as the running
+	// instance would overwrite it on its next save.
+	lock, err := nonota.LockInstance(filename)
+	if err == nonota.ErrLocked {
+		return fmt.Errorf("board %s is open in another nonota instance", filename)
+	}
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	storage, err := nonota.OpenStorage(filename, nonota.StorageOptions{
+		Backups: c.opts.Backups,
+	})
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	if err := storage.Save(board); err != nil {
+		return err
+	}
+	fmt.Printf("Imported %s into %s\n", c.Args.Source, filename)
+	return nil
+}
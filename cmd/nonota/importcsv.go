@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/matheusd/nonota"
+)
+
+type importCSVCmd struct {
+	opts *opts
+
+	Project     string `long:"project" description:"Column with the project, imported as the list of the entry" default:"Project"`
+	Description string `long:"description" description:"Column with the description, imported as the task of the entry" default:"Description"`
+	Start       string `long:"start" description:"Column with the start time (join columns with +, as in \"Start date+Start time\")" default:"Start date+Start time"`
+	End         string `long:"end" description:"Column with the end time" default:"End date+End time"`
+	Duration    string `long:"duration" description:"Column with the duration (hh:mm:ss, decimal hours or a duration such as 1h30m)" default:"Duration"`
+	Tags        string `long:"tags" description:"Column with the comma separated tags of the task" default:"Tags"`
+	TimeFormat  string `long:"time-format" description:"Layout of the start and end times, as in Go's time.Parse (by default the common formats are tried)"`
+	List        string `long:"list" description:"List of the entries without a project" default:"Imported"`
+	DryRun      bool   `short:"n" long:"dry-run" description:"Show what would be imported without changing the board"`
+
+	Args struct {
+		Source string `positional-arg-name:"source" description:"CSV file with the time entries (such as a Toggl or Clockify export)"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+// Execute adds the time entries of the given csv file to the board.
+func (c *importCSVCmd) Execute(args []string) error {
+	filename := c.opts.Filename
+	if !c.DryRun {
+		// Importing while the board is open would be pointless, as the
+		// running instance would overwrite it on its next save.
+		lock, err := nonota.LockInstance(filename)
+		if err == nonota.ErrLocked {
+			return fmt.Errorf("board %s is open in another nonota instance", filename)
+		}
+		if err != nil {
+			return err
+		}
+		defer lock.Unlock()
+	}
+
+	storage, err := nonota.OpenStorage(filename, nonota.StorageOptions{
+		Backups: c.opts.Backups,
+	})
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	board, err := storage.Load()
+	if err != nil {
+		return err
+	}
+
+	// Times without a time zone are in the one of the board.
+	loc := board.Settings.Location()
+	if c.opts.TZ != "" {
+		loc, err = time.LoadLocation(c.opts.TZ)
+		if err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Open(c.Args.Source)
+	if err != nil {
+		return err
+	}
+	entries, err := board.ImportCSV(f, nonota.CSVMapping{
+		Project:     c.Project,
+		Description: c.Description,
+		Start:       c.Start,
+		End:         c.End,
+		Duration:    c.Duration,
+		Tags:        c.Tags,
+		TimeFormat:  c.TimeFormat,
+		Location:    loc,
+		DefaultList: c.List,
+	})
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %v", c.Args.Source, err)
+	}
+
+	dtFormat := "2006-01-02 15:04"
+	added, duplicates := 0, 0
+	for _, e := range entries {
+		mark := "+"
+		if e.Duplicate {
+			mark = "="
+			duplicates++
+		} else {
+			added++
+		}
+		var created string
+		switch {
+		case e.NewList:
+			created = " (new list)"
+		case e.NewTask:
+			created = " (new task)"
+		}
+		tt := e.TaskTime
+		fmt.Printf("%s %s - %s %8s  %s / %s%s\n", mark,
+			tt.Start.In(loc).Format(dtFormat), tt.End.In(loc).Format(dtFormat),
+			tt.Duration, e.List.Title, e.Task.Title, created)
+	}
+	for _, issue := range board.Validate() {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", issue)
+	}
+
+	if c.DryRun {
+		fmt.Printf("Would import %d entries into %s (%d duplicates skipped)\n",
+			added, filename, duplicates)
+		return nil
+	}
+	if added > 0 {
+		if err := storage.Save(board); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Imported %d entries into %s (%d duplicates skipped)\n",
+		added, filename, duplicates)
+	return nil
+}
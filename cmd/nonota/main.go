@@ -7,57 +7,104 @@ import (
 
 	flags "github.com/jessevdk/go-flags"
 	"github.com/matheusd/nonota"
+	_ "github.com/matheusd/nonota/sqlite"
 	nonotaui "github.com/matheusd/nonota/ui"
 )
 
 type opts struct {
 	Filename string `short:"f" long:"filename" description:"Filename of the board to use"`
-	Previous bool   `long:"previous" description:"See the board for the previous month"`
-	Date     string `long:"date" description:"Year and month to generate billing (in the YYYY-MM format)"`
+	Previous bool   `long:"previous" description:"See the board for the previous billing period"`
+	Date     string `long:"date" description:"Date within the billing period to see (in the YYYY-MM-DD or YYYY-MM format)"`
+	Backups  int    `long:"backups" description:"Number of backups of the board file to keep"`
+	TZ       string `long:"tz" description:"Time zone used to compute days, weeks and billing periods (overrides the board setting)"`
+
+	Restore restoreCmd `command:"restore" description:"List the backups of the board or restore one of them"`
+	Convert convertCmd `command:"convert" description:"Copy the board into a new file, possibly using another storage (such as SQLite)"`
+	Check   checkCmd   `command:"check" description:"Check the board for inconsistencies such as overlapping times"`
+	Report  reportCmd  `command:"report" description:"Print the times of the billing period grouped by list, task, tag, day or week"`
+	Export  exportCmd  `command:"export" description:"Write the whole board as json (or yaml)"`
+	Import  importCmd  `command:"import" description:"Replace the board with one exported as json"`
+
+	ImportCSV importCSVCmd `command:"import-csv" description:"Add the time entries of a csv file exported by another time tracker"`
 }
 
 func getCmdOpts() *opts {
 	cmdOpts := &opts{
 		Filename: "nonota-board.yml",
+		Backups:  10,
 	}
+	cmdOpts.Restore.opts = cmdOpts
+	cmdOpts.Convert.opts = cmdOpts
+	cmdOpts.Check.opts = cmdOpts
+	cmdOpts.Report.opts = cmdOpts
+	cmdOpts.Export.opts = cmdOpts
+	cmdOpts.Import.opts = cmdOpts
+	cmdOpts.ImportCSV.opts = cmdOpts
+
 	parser := flags.NewParser(cmdOpts, flags.Default)
+	parser.SubcommandsOptional = true
 	_, err := parser.Parse()
 	if err != nil {
 		e, ok := err.(*flags.Error)
 		if ok && e.Type == flags.ErrHelp {
 			os.Exit(0)
 		}
-		fmt.Printf("Argument error: %v\n", e)
+		if !ok {
+			// Error returned by a command, already printed by the
+			// parser.
+			os.Exit(1)
+		}
+		fmt.Printf("Argument error: %v\n", err)
 		os.Exit(1)
 	}
 
+	// Commands are executed by the parser itself, so there's nothing else to
+	// do after one was run.
+	if parser.Active != nil {
+		os.Exit(0)
+	}
+
 	return cmdOpts
 }
 
 func main() {
 	opts := getCmdOpts()
 
-	refTime := time.Now()
-	if opts.Previous {
-		// Go back a day prior to the start of the current period
-		// to get a date in the previous billing period
-		refTime = nonota.StartOfBilling(nonota.StartOfBilling(refTime).Add(time.Hour * -24))
-	} else if opts.Date != "" {
-		ym, err := time.Parse("2006-01", opts.Date)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		refTime = nonota.StartOfBilling(ym)
+	// Only a single interactive instance may modify the board. Others get
+	// to see it in read-only mode.
+	readOnly := false
+	lock, err := nonota.LockInstance(opts.Filename)
+	if err == nonota.ErrLocked {
+		readOnly = true
+	} else if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
 	}
+	defer lock.Unlock()
 
-	board, err := nonota.BoardFromFile(opts.Filename)
+	storage, err := nonota.OpenStorage(opts.Filename, nonota.StorageOptions{
+		Backups: opts.Backups,
+	})
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+	defer storage.Close()
 
-	ui := nonotaui.New(board, opts.Filename, refTime)
+	board, err := storage.Load()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	refTime, err := referenceTime(board, opts)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	ui := nonotaui.New(storage, board, opts.Filename, refTime)
+	ui.SetReadOnly(readOnly)
 
 	err = ui.Run()
 	if err != nil {
@@ -65,3 +112,34 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// referenceTime returns the time used to select the billing period displayed
+// by the board. It's in the time zone of the board, unless overridden by
+// --tz.
+func referenceTime(board *nonota.Board, opts *opts) (time.Time, error) {
+	loc := board.Settings.Location()
+	if opts.TZ != "" {
+		var err error
+		loc, err = time.LoadLocation(opts.TZ)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	billing := board.Settings.Billing
+	now := time.Now().In(loc)
+	switch {
+	case opts.Previous:
+		return billing.Previous(now), nil
+	case opts.Date != "":
+		date, err := time.ParseInLocation("2006-01-02", opts.Date, loc)
+		if err != nil {
+			date, err = time.ParseInLocation("2006-01", opts.Date, loc)
+		}
+		if err != nil {
+			return now, fmt.Errorf("invalid date %q", opts.Date)
+		}
+		return billing.Start(date), nil
+	}
+	return now, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/matheusd/nonota"
+	"github.com/matheusd/nonota/report"
+)
+
+type reportCmd struct {
+	opts *opts
+
+	Group  string `long:"group" description:"Comma-separated levels of the report: list, task, tag, day and week" default:"list,task"`
+	Format string `long:"format" description:"Output format (text or csv)" default:"text"`
+	Tag    string `long:"tag" description:"Only report tasks with the given tag"`
+}
+
+// Execute prints the totals of the billing period selected by --previous and
+// --date, grouped as requested.
+func (c *reportCmd) Execute(args []string) error {
+	groups, err := report.ParseGroups(c.Group)
+	if err != nil {
+		return err
+	}
+	renderer, ok := report.Renderers[c.Format]
+	if !ok {
+		var formats []string
+		for name := range report.Renderers {
+			formats = append(formats, name)
+		}
+		sort.Strings(formats)
+		return fmt.Errorf("unknown format %q (use one of %s)", c.Format,
+			strings.Join(formats, ", "))
+	}
+
+	storage, err := nonota.OpenStorage(c.opts.Filename, nonota.StorageOptions{})
+	if err != nil {
+		return err
+	}
+	board, err := storage.Load()
+	storage.Close()
+	if err != nil {
+		return err
+	}
+
+	refTime, err := referenceTime(board, c.opts)
+	if err != nil {
+		return err
+	}
+	billing := board.Settings.Billing
+	rep := report.New(board, report.Options{
+		From:   billing.Start(refTime),
+		To:     billing.End(refTime),
+		Groups: groups,
+		Tag:    c.Tag,
+	})
+	return renderer.Render(os.Stdout, rep)
+}
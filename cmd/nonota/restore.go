@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/matheusd/nonota"
+)
+
+type restoreCmd struct {
+	opts *opts
+
+	Args struct {
+		Backup string `positional-arg-name:"backup" description:"Number (as shown in the list) or filename of the backup to restore"`
+	} `positional-args:"yes"`
+}
+
+// Execute lists the available backups of the board or restores one of them
+// if specified.
+func (c *restoreCmd) Execute(args []string) error {
+	filename := c.opts.Filename
+	backups, err := nonota.ListBackups(filename)
+	if err != nil {
+		return err
+	}
+
+	if c.Args.Backup == "" {
+		if len(backups) == 0 {
+			fmt.Printf("No backups found for %s\n", filename)
+			return nil
+		}
+		fmt.Printf("Backups of %s (newest first):\n", filename)
+		for i, b := range backups {
+			fmt.Printf("%3d  %s\n", i+1, b)
+		}
+		return nil
+	}
+
+	backup := c.Args.Backup
+	if i, err := strconv.Atoi(backup); err == nil {
+		if i < 1 || i > len(backups) {
+			return fmt.Errorf("invalid backup number %d", i)
+		}
+		backup = backups[i-1]
+	}
+
+	// Restoring while the board is open would be pointless, as the running
+	// instance would overwrite it on its next save.
+	lock, err := nonota.LockInstance(filename)
+	if err == nonota.ErrLocked {
+		return fmt.Errorf("board %s is open in another nonota instance", filename)
+	}
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	// Backup the current board first, so that the restore can be undone.
+	// No backups are pruned here, as that could remove the one being
+	// restored.
+	keep := c.opts.Backups
+	if keep < len(backups) {
+		keep = len(backups)
+	}
+	if err := nonota.BackupFile(filename, keep+1); err != nil {
+		return err
+	}
+
+	if err := nonota.RestoreBackup(filename, backup); err != nil {
+		return err
+	}
+	fmt.Printf("Restored %s from %s\n", filename, backup)
+	return nil
+}
@@ -0,0 +1,358 @@
+package nonota
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSVMapping describes which columns of a csv file (such as the ones exported
+// by Toggl or Clockify) hold the fields of the imported time entries.
+//
+// Columns are identified by the name in the header of the file, ignoring case.
+// Values split in multiple columns (such as a date and a time) may be joined
+// by listing the columns separated by "+", as in "Start date+Start time".
+type CSVMapping struct {
+	// Project is the column with the title of the list of the entry.
+	// Entries without a project are added to DefaultList.
+	Project string
+
+	// Description is the column with the title of the task of the entry.
+	Description string
+
+	Start    string
+	End      string
+	Duration string
+
+	// Tags is the column with the comma separated tags of the task.
+	Tags string
+
+	// TimeFormat is the layout (as in time.Parse) of the start and end
+	// times. If empty, the most common layouts are tried.
+	TimeFormat string
+
+	// Location is the time zone of times which don't specify one. Defaults
+	// to UTC.
+	Location *time.Location
+
+	DefaultList string
+}
+
+// DefaultCSVMapping returns the mapping of the detailed reports exported by
+// Toggl. Clockify exports use the same column names (only differing in case).
+func DefaultCSVMapping() CSVMapping {
+	return CSVMapping{
+		Project:     "Project",
+		Description: "Description",
+		Start:       "Start date+Start time",
+		End:         "End date+End time",
+		Duration:    "Duration",
+		Tags:        "Tags",
+		DefaultList: "Imported",
+	}
+}
+
+// csvTimeFormats are the layouts tried when the mapping doesn't specify one.
+var csvTimeFormats = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"01/02/2006 03:04:05 PM",
+	"01/02/2006 03:04 PM",
+	"01/02/2006 15:04:05",
+	"01/02/2006 15:04",
+}
+
+// CSVEntry is a time entry read from a csv file.
+type CSVEntry struct {
+	// Line is the line of the entry in the csv file.
+	Line int
+
+	List *List
+	Task *Task
+
+	// NewList and NewTask are true if the list or task of the entry was
+	// created by the import.
+	NewList bool
+	NewTask bool
+
+	TaskTime *TaskTime
+
+	// Duplicate is true if the task already had an entry with the same
+	// timeframe, in which case the entry was not added.
+	Duplicate bool
+}
+
+// csvColumns are the indexes of the columns of each field of a mapping.
+type csvColumns struct {
+	project, description, start, end, duration, tags []int
+}
+
+// columns finds the columns of the mapping in the header of a csv file.
+func (m *CSVMapping) columns(header []string) (*csvColumns, error) {
+	find := func(field, spec string, required bool) ([]int, error) {
+		if spec == "" {
+			if required {
+				return nil, fmt.Errorf("no column specified for %s", field)
+			}
+			return nil, nil
+		}
+		var idx []int
+		for _, name := range strings.Split(spec, "+") {
+			name = strings.TrimSpace(name)
+			i := 0
+			for ; i < len(header); i++ {
+				if strings.EqualFold(strings.TrimSpace(header[i]), name) {
+					break
+				}
+			}
+			if i == len(header) {
+				if !required {
+					return nil, nil
+				}
+				return nil, fmt.Errorf("column %q (%s) not found", name, field)
+			}
+			idx = append(idx, i)
+		}
+		return idx, nil
+	}
+
+	cols := &csvColumns{}
+	var err error
+	fields := []struct {
+		field    string
+		spec     string
+		required bool
+		idx      *[]int
+	}{
+		{"project", m.Project, false, &cols.project},
+		{"description", m.Description, true, &cols.description},
+		{"start", m.Start, true, &cols.start},
+		{"end", m.End, false, &cols.end},
+		{"duration", m.Duration, false, &cols.duration},
+		{"tags", m.Tags, false, &cols.tags},
+	}
+	for _, f := range fields {
+		if *f.idx, err = find(f.field, f.spec, f.required); err != nil {
+			return nil, err
+		}
+	}
+	if cols.end == nil && cols.duration == nil {
+		return nil, fmt.Errorf("either the end or the duration column is needed")
+	}
+	return cols, nil
+}
+
+// parseTime parses a start or end time of an entry.
+func (m *CSVMapping) parseTime(s string) (time.Time, error) {
+	loc := m.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	if m.TimeFormat != "" {
+		return time.ParseInLocation(m.TimeFormat, s, loc)
+	}
+	for _, layout := range csvTimeFormats {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown time format %q", s)
+}
+
+// ParseCSVDuration parses a duration as found in csv exports of time trackers:
+// either "hh:mm[:ss]", decimal hours ("1.5") or a Go duration ("1h30m").
+func ParseCSVDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, ":") {
+		parts := strings.Split(s, ":")
+		if len(parts) > 3 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		var d time.Duration
+		units := []time.Duration{time.Hour, time.Minute, time.Second}
+		for i, p := range parts {
+			n, err := strconv.Atoi(p)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			d += time.Duration(n) * units[i]
+		}
+		return d, nil
+	}
+	if h, err := strconv.ParseFloat(s, 64); err == nil {
+		if h < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(h * float64(time.Hour)).Round(time.Second), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// ImportCSV reads the time entries of a csv file and adds them to the board,
+// creating the lists and tasks (matched by title) that don't exist yet.
+//
+// Entries with the same timeframe as an existing entry of their task are
+// considered duplicates and skipped, so importing the same file multiple times
+// only adds its entries once. Either all entries are added or, in case of
+// errors, none of them. The returned entries include the duplicates.
+func (b *Board) ImportCSV(r io.Reader, m CSVMapping) ([]*CSVEntry, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("empty csv file")
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Some tools start their files with a byte order mark.
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	cols, err := m.columns(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*CSVEntry
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		value := func(idx []int) string {
+			vals := make([]string, 0, len(idx))
+			for _, i := range idx {
+				if i < len(record) {
+					vals = append(vals, strings.TrimSpace(record[i]))
+				}
+			}
+			return strings.TrimSpace(strings.Join(vals, " "))
+		}
+
+		e, err := m.entry(value, cols)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		e.Line = line
+		entries = append(entries, e)
+	}
+
+	// Only change the board once every line has been read successfully.
+	for _, e := range entries {
+		b.addCSVEntry(e)
+	}
+	return entries, nil
+}
+
+// entry returns the entry of a line of the csv file. The list and task of the
+// returned entry are not yet resolved: they only hold the titles.
+func (m *CSVMapping) entry(value func([]int) string, cols *csvColumns) (*CSVEntry, error) {
+	tt := &TaskTime{}
+	var err error
+	if tt.Start, err = m.parseTime(value(cols.start)); err != nil {
+		return nil, err
+	}
+	hasEnd := cols.end != nil && value(cols.end) != ""
+	if hasEnd {
+		if tt.End, err = m.parseTime(value(cols.end)); err != nil {
+			return nil, err
+		}
+	}
+	hasDuration := cols.duration != nil && value(cols.duration) != ""
+	if hasDuration {
+		if tt.Duration, err = ParseCSVDuration(value(cols.duration)); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case hasEnd && !hasDuration:
+		tt.Duration = tt.End.Sub(tt.Start)
+	case !hasEnd && hasDuration:
+		tt.End = tt.Start.Add(tt.Duration)
+	case !hasEnd && !hasDuration:
+		return nil, fmt.Errorf("entry has neither end nor duration")
+	}
+	if err := tt.Check(); err != nil {
+		return nil, err
+	}
+
+	project := value(cols.project)
+	if project == "" {
+		project = m.DefaultList
+	}
+	if project == "" {
+		project = "Imported"
+	}
+	title := value(cols.description)
+	if title == "" {
+		title = "New Task"
+	}
+
+	task := &Task{Title: title, Tags: ParseTags(title)}
+	for _, tag := range strings.Split(value(cols.tags), ",") {
+		if tag = strings.TrimPrefix(strings.TrimSpace(tag), "#"); tag != "" {
+			task.AddTag(tag)
+		}
+	}
+	return &CSVEntry{
+		List:     &List{Title: project},
+		Task:     task,
+		TaskTime: tt,
+	}, nil
+}
+
+// addCSVEntry resolves the list and task of the entry and adds it to the board
+// unless it's a duplicate.
+func (b *Board) addCSVEntry(e *CSVEntry) {
+	var list *List
+	for _, l := range b.Lists {
+		if l.Title == e.List.Title {
+			list = l
+			break
+		}
+	}
+	if list == nil {
+		list = b.AppendNewList()
+		list.Title = e.List.Title
+		e.NewList = true
+	}
+
+	var task *Task
+	for _, t := range list.Tasks {
+		if t.Title == e.Task.Title {
+			task = t
+			break
+		}
+	}
+	if task == nil {
+		task = b.AppendNewTask(list)
+		task.Title = e.Task.Title
+		e.NewTask = true
+	}
+	for _, tag := range e.Task.Tags {
+		task.AddTag(tag)
+	}
+	e.List, e.Task = list, task
+
+	for _, tt := range task.Times {
+		if tt.Start.Equal(e.TaskTime.Start) && tt.End.Equal(e.TaskTime.End) {
+			e.Duplicate = true
+			return
+		}
+	}
+	task.AddTaskTime(e.TaskTime)
+}
@@ -0,0 +1,119 @@
+package nonota
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCSVDuration(t *testing.T) {
+	tests := []struct {
+		s    string
+		want time.Duration
+	}{
+		{"01:30:00", 90 * time.Minute},
+		{"2:05", 2*time.Hour + 5*time.Minute},
+		{"1.5", 90 * time.Minute},
+		{"45m", 45 * time.Minute},
+	}
+	for _, tc := range tests {
+		d, err := ParseCSVDuration(tc.s)
+		if err != nil {
+			t.Fatalf("unexpected error parsing %q: %v", tc.s, err)
+		}
+		if d != tc.want {
+			t.Fatalf("unexpected duration of %q: %s", tc.s, d)
+		}
+	}
+	for _, s := range []string{"", "1:2:3:4", "-1", "x"} {
+		if _, err := ParseCSVDuration(s); err == nil {
+			t.Fatalf("expected error parsing %q", s)
+		}
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	toggl := "\ufeffUser,Project,Description,Start date,Start time,End date,End time,Duration,Tags\n" +
+		"me,ACME,Write docs,2019-03-04,10:00:00,2019-03-04,11:30:00,01:30:00,\"docs, billable\"\n" +
+		"me,,Standup,2019-03-04,12:00:00,2019-03-04,12:15:00,00:15:00,\n" +
+		"me,ACME,Write docs,2019-03-05,09:00:00,2019-03-05,10:00:00,00:45:00,\n"
+
+	docs := &Task{ID: NewID(), Title: "Write docs"}
+	b := &Board{ID: NewID(), Lists: []*List{{
+		ID:    NewID(),
+		Title: "ACME",
+		Tasks: []*Task{docs},
+	}}}
+
+	entries, err := b.ImportCSV(strings.NewReader(toggl), DefaultCSVMapping())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+	if entries[0].Task != docs || entries[0].NewTask || entries[0].Line != 2 {
+		t.Fatalf("unexpected first entry %#v", entries[0])
+	}
+	if !docs.HasTag("docs") || !docs.HasTag("billable") {
+		t.Fatalf("unexpected tags %v", docs.Tags)
+	}
+	if len(docs.Times) != 2 || docs.Times[1].Duration != 45*time.Minute {
+		t.Fatalf("unexpected times %v", docs.Times)
+	}
+	if len(b.Lists) != 2 || b.Lists[1].Title != "Imported" || !entries[1].NewList ||
+		b.Lists[1].Tasks[0].Title != "Standup" {
+		t.Fatalf("unexpected lists %v", b.Lists)
+	}
+	want := time.Date(2019, 3, 4, 12, 15, 0, 0, time.UTC)
+	if tt := b.Lists[1].Tasks[0].Times[0]; !tt.End.Equal(want) {
+		t.Fatalf("unexpected end %s", tt.End)
+	}
+
+	// Importing the same file again doesn't add anything.
+	entries, err = b.ImportCSV(strings.NewReader(toggl), DefaultCSVMapping())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if !e.Duplicate {
+			t.Fatalf("expected duplicate entry on line %d", e.Line)
+		}
+	}
+	if len(docs.Times) != 2 || len(b.Lists) != 2 {
+		t.Fatalf("duplicates added to the board")
+	}
+
+	// Custom mapping with a duration in decimal hours and no end.
+	custom := "project,task,when,hours\n" +
+		"ACME,Review,2019-03-06T10:00:00Z,0.5\n"
+	m := CSVMapping{
+		Project:     "project",
+		Description: "task",
+		Start:       "when",
+		Duration:    "hours",
+	}
+	entries, err = b.ImportCSV(strings.NewReader(custom), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt := entries[0].TaskTime
+	if entries[0].List != b.Lists[0] || tt.Duration != 30*time.Minute ||
+		tt.End.Sub(tt.Start) != 30*time.Minute {
+		t.Fatalf("unexpected entry %#v", entries[0])
+	}
+
+	// Errors leave the board untouched.
+	bad := "Project,Description,Start date,Start time,End date,End time,Duration\n" +
+		"ACME,New,2019-03-07,10:00:00,2019-03-07,11:00:00,01:00:00\n" +
+		"ACME,New,2019-03-07,12:00:00,2019-03-07,11:00:00,\n"
+	if _, err := b.ImportCSV(strings.NewReader(bad), DefaultCSVMapping()); err == nil {
+		t.Fatalf("expected error importing end before start")
+	}
+	if len(b.Lists[0].Tasks) != 2 {
+		t.Fatalf("board changed by failed import")
+	}
+	if _, err := b.ImportCSV(strings.NewReader("Project,Start\n"), DefaultCSVMapping()); err == nil {
+		t.Fatalf("expected error on missing columns")
+	}
+}
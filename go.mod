@@ -9,6 +9,7 @@ require (
 	github.com/jessevdk/go-flags v1.4.0
 	github.com/rivo/tview v0.0.0-20190310113948-3938f6008527
 	gopkg.in/yaml.v2 v2.2.2
+	modernc.org/sqlite v1.29.0
 )
 
 replace github.com/rivo/tview => github.com/Bios-Marcel/tview v0.0.0-20190309205413-78747d400c68
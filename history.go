@@ -0,0 +1,858 @@
+package nonota
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// DefaultHistorySize is the default maximum number of actions kept in the
+// undo history.
+const DefaultHistorySize = 100
+
+// ChangeKind identifies the kind of an elementary change to a board.
+type ChangeKind string
+
+const (
+	// ChangeMoveList moves list ListID from FromIndex to ToIndex.
+	ChangeMoveList ChangeKind = "moveList"
+
+	// ChangeMoveTask moves task TaskID from FromIndex of list FromListID
+	// to ToIndex of list ToListID.
+	ChangeMoveTask ChangeKind = "moveTask"
+
+	// ChangeInsertList inserts List at ToIndex.
+	ChangeInsertList ChangeKind = "insertList"
+
+	// ChangeRemoveList removes List from FromIndex.
+	ChangeRemoveList ChangeKind = "removeList"
+
+	// ChangeInsertTask inserts Task at ToIndex of list ToListID.
+	ChangeInsertTask ChangeKind = "insertTask"
+
+	// ChangeRemoveTask removes Task from FromIndex of list FromListID.
+	ChangeRemoveTask ChangeKind = "removeTask"
+
+	// ChangeEditList changes the fields of list ListID from OldList to
+	// NewList (its tasks are not changed).
+	ChangeEditList ChangeKind = "editList"
+
+	// ChangeEditTask changes the fields of task TaskID from OldTask to
+	// NewTask (its times are not changed).
+	ChangeEditTask ChangeKind = "editTask"
+
+	// ChangeInsertTaskTime inserts TaskTime at ToIndex of the times of
+	// task TaskID.
+	ChangeInsertTaskTime ChangeKind = "insertTaskTime"
+
+	// ChangeRemoveTaskTime removes TaskTime from FromIndex of the times of
+	// task TaskID.
+	ChangeRemoveTaskTime ChangeKind = "removeTaskTime"
+
+	// ChangeEditTaskTime changes a time entry of task TaskID from
+	// OldTaskTime to NewTaskTime.
+	ChangeEditTaskTime ChangeKind = "editTaskTime"
+)
+
+// Change is an elementary, reversible change to a board. Items are referenced
+// by id, so that changes can be persisted and applied to a board reloaded
+// from its file. Which fields are used depends on the kind of the change.
+type Change struct {
+	Kind ChangeKind
+
+	ListID     string `yaml:",omitempty"`
+	TaskID     string `yaml:",omitempty"`
+	FromListID string `yaml:",omitempty"`
+	ToListID   string `yaml:",omitempty"`
+	FromIndex  int
+	ToIndex    int
+
+	List     *List     `yaml:",omitempty"`
+	Task     *Task     `yaml:",omitempty"`
+	TaskTime *TaskTime `yaml:",omitempty"`
+
+	OldList *List `yaml:",omitempty"`
+	NewList *List `yaml:",omitempty"`
+	OldTask *Task `yaml:",omitempty"`
+	NewTask *Task `yaml:",omitempty"`
+
+	OldTaskTime *TaskTime `yaml:",omitempty"`
+	NewTaskTime *TaskTime `yaml:",omitempty"`
+}
+
+// Inverse returns the change that reverts this change.
+func (c *Change) Inverse() *Change {
+	inv := *c
+	inv.FromListID, inv.ToListID = c.ToListID, c.FromListID
+	inv.FromIndex, inv.ToIndex = c.ToIndex, c.FromIndex
+	inv.OldList, inv.NewList = c.NewList, c.OldList
+	inv.OldTask, inv.NewTask = c.NewTask, c.OldTask
+	inv.OldTaskTime, inv.NewTaskTime = c.NewTaskTime, c.OldTaskTime
+
+	switch c.Kind {
+	case ChangeInsertList:
+		inv.Kind = ChangeRemoveList
+	case ChangeRemoveList:
+		inv.Kind = ChangeInsertList
+	case ChangeInsertTask:
+		inv.Kind = ChangeRemoveTask
+	case ChangeRemoveTask:
+		inv.Kind = ChangeInsertTask
+	case ChangeInsertTaskTime:
+		inv.Kind = ChangeRemoveTaskTime
+	case ChangeRemoveTaskTime:
+		inv.Kind = ChangeInsertTaskTime
+	}
+
+	return &inv
+}
+
+// errBoardChanged is returned when a change can't be applied because the
+// board is not in the state the change expects.
+func errBoardChanged(what, id string) error {
+	return fmt.Errorf("%s %s is not where expected; the board was changed", what, id)
+}
+
+// Apply applies the change to the given board. An error is returned (and the
+// board is left untouched) if the board is not in the state the change
+// expects.
+func (c *Change) Apply(b *Board) error {
+	switch c.Kind {
+	case ChangeMoveList:
+		if c.FromIndex >= len(b.Lists) || b.Lists[c.FromIndex].ID != c.ListID ||
+			c.ToIndex >= len(b.Lists) {
+			return errBoardChanged("list", c.ListID)
+		}
+		l := b.Lists[c.FromIndex]
+		b.Lists = removeList(b.Lists, c.FromIndex)
+		b.Lists = insertList(b.Lists, c.ToIndex, l)
+
+	case ChangeMoveTask:
+		from, to := b.ListByID(c.FromListID), b.ListByID(c.ToListID)
+		if from == nil || to == nil || c.FromIndex >= len(from.Tasks) ||
+			from.Tasks[c.FromIndex].ID != c.TaskID {
+			return errBoardChanged("task", c.TaskID)
+		}
+		t := from.Tasks[c.FromIndex]
+		from.Tasks = removeTask(from.Tasks, c.FromIndex)
+		if c.ToIndex > len(to.Tasks) {
+			// Revert the removal before failing.
+			from.Tasks = insertTask(from.Tasks, c.FromIndex, t)
+			return errBoardChanged("task", c.TaskID)
+		}
+		to.Tasks = insertTask(to.Tasks, c.ToIndex, t)
+
+	case ChangeInsertList:
+		if c.ToIndex > len(b.Lists) || b.ListByID(c.List.ID) != nil {
+			return errBoardChanged("list", c.List.ID)
+		}
+		b.Lists = insertList(b.Lists, c.ToIndex, c.List.clone())
+
+	case ChangeRemoveList:
+		if c.FromIndex >= len(b.Lists) || b.Lists[c.FromIndex].ID != c.List.ID {
+			return errBoardChanged("list", c.List.ID)
+		}
+		b.Lists = removeList(b.Lists, c.FromIndex)
+
+	case ChangeInsertTask:
+		l := b.ListByID(c.ToListID)
+		if l == nil || c.ToIndex > len(l.Tasks) || b.TaskByID(c.Task.ID) != nil {
+			return errBoardChanged("task", c.Task.ID)
+		}
+		l.Tasks = insertTask(l.Tasks, c.ToIndex, c.Task.clone())
+
+	case ChangeRemoveTask:
+		l := b.ListByID(c.FromListID)
+		if l == nil || c.FromIndex >= len(l.Tasks) ||
+			l.Tasks[c.FromIndex].ID != c.Task.ID {
+			return errBoardChanged("task", c.Task.ID)
+		}
+		l.Tasks = removeTask(l.Tasks, c.FromIndex)
+
+	case ChangeEditList:
+		l := b.ListByID(c.ListID)
+		if l == nil {
+			return errBoardChanged("list", c.ListID)
+		}
+		l.setFields(c.NewList)
+
+	case ChangeEditTask:
+		t := b.TaskByID(c.TaskID)
+		if t == nil {
+			return errBoardChanged("task", c.TaskID)
+		}
+		t.setFields(c.NewTask)
+
+	case ChangeInsertTaskTime:
+		t := b.TaskByID(c.TaskID)
+		if t == nil || c.ToIndex > len(t.Times) ||
+			t.TaskTimeByID(c.TaskTime.ID) != nil {
+			return errBoardChanged("time entry", c.TaskTime.ID)
+		}
+		tt := *c.TaskTime
+		t.Times = insertTaskTime(t.Times, c.ToIndex, &tt)
+
+	case ChangeRemoveTaskTime:
+		t := b.TaskByID(c.TaskID)
+		if t == nil || c.FromIndex >= len(t.Times) ||
+			t.Times[c.FromIndex].ID != c.TaskTime.ID {
+			return errBoardChanged("time entry", c.TaskTime.ID)
+		}
+		t.Times = removeTaskTime(t.Times, c.FromIndex)
+
+	case ChangeEditTaskTime:
+		t := b.TaskByID(c.TaskID)
+		if t == nil {
+			return errBoardChanged("task", c.TaskID)
+		}
+		tt := t.TaskTimeByID(c.NewTaskTime.ID)
+		if tt == nil {
+			return errBoardChanged("time entry", c.NewTaskTime.ID)
+		}
+		*tt = *c.NewTaskTime
+
+	default:
+		return fmt.Errorf("unknown change kind %q", c.Kind)
+	}
+
+	return nil
+}
+
+func insertList(lists []*List, i int, l *List) []*List {
+	lists = append(lists, nil)
+	copy(lists[i+1:], lists[i:])
+	lists[i] = l
+	return lists
+}
+
+func removeList(lists []*List, i int) []*List {
+	return append(lists[:i], lists[i+1:]...)
+}
+
+func insertTask(tasks []*Task, i int, t *Task) []*Task {
+	tasks = append(tasks, nil)
+	copy(tasks[i+1:], tasks[i:])
+	tasks[i] = t
+	return tasks
+}
+
+func removeTask(tasks []*Task, i int) []*Task {
+	return append(tasks[:i], tasks[i+1:]...)
+}
+
+func insertTaskTime(times []*TaskTime, i int, tt *TaskTime) []*TaskTime {
+	times = append(times, nil)
+	copy(times[i+1:], times[i:])
+	times[i] = tt
+	return times
+}
+
+func removeTaskTime(times []*TaskTime, i int) []*TaskTime {
+	return append(times[:i], times[i+1:]...)
+}
+
+// clone returns a deep copy of the task.
+func (t *Task) clone() *Task {
+	c := *t
+	c.Tags = append([]string(nil), t.Tags...)
+	c.Times = make([]*TaskTime, len(t.Times))
+	for i, tt := range t.Times {
+		ttc := *tt
+		c.Times[i] = &ttc
+	}
+	return &c
+}
+
+// fields returns a copy of the task without its times.
+func (t *Task) fields() *Task {
+	c := *t
+	c.Tags = append([]string(nil), t.Tags...)
+	c.Times = nil
+	return &c
+}
+
+// setFields sets all fields of the task (except its id and times) to the ones
+// of src.
+func (t *Task) setFields(src *Task) {
+	id, times := t.ID, t.Times
+	*t = *src
+	t.ID, t.Times = id, times
+}
+
+// clone returns a deep copy of the list.
+func (l *List) clone() *List {
+	c := *l
+	c.Tasks = make([]*Task, len(l.Tasks))
+	for i, t := range l.Tasks {
+		c.Tasks[i] = t.clone()
+	}
+	return &c
+}
+
+// fields returns a copy of the list without its tasks.
+func (l *List) fields() *List {
+	c := *l
+	c.Tasks = nil
+	return &c
+}
+
+// setFields sets all fields of the list (except its id and tasks) to the ones
+// of src.
+func (l *List) setFields(src *List) {
+	id, tasks := l.ID, l.Tasks
+	*l = *src
+	l.ID, l.Tasks = id, tasks
+}
+
+// listIndex returns the index of the list in the board or -1.
+func (b *Board) listIndex(list *List) int {
+	for i, l := range b.Lists {
+		if l == list {
+			return i
+		}
+	}
+	return -1
+}
+
+// taskPosition returns the list that contains the task and its index in it.
+func (b *Board) taskPosition(task *Task) (*List, int) {
+	for _, l := range b.Lists {
+		for i, t := range l.Tasks {
+			if t == task {
+				return l, i
+			}
+		}
+	}
+	return nil, -1
+}
+
+// Action is a single user operation on a board, made of one or more changes.
+type Action struct {
+	Description string
+	Time        time.Time
+	Changes     []*Change
+}
+
+// History records the actions performed on a board, so that they can be
+// undone and redone.
+//
+// The board should only be modified through the methods of the history (or
+// by applying changes with Do), otherwise the recorded actions may no longer
+// be applicable.
+type History struct {
+	// BoardID is the id of the board the actions were performed on.
+	BoardID string
+
+	Undo []*Action
+	Redo []*Action
+
+	// MaxSize is the maximum number of actions kept for undoing.
+	MaxSize int `yaml:"-"`
+}
+
+// NewHistory returns an empty history for the given board.
+func NewHistory(b *Board) *History {
+	return &History{
+		BoardID: b.ID,
+		MaxSize: DefaultHistorySize,
+	}
+}
+
+// record adds an action (whose changes were already applied to the board) to
+// the history.
+func (h *History) record(description string, changes ...*Change) {
+	if len(changes) == 0 {
+		return
+	}
+
+	h.Undo = append(h.Undo, &Action{
+		Description: description,
+		Time:        time.Now(),
+		Changes:     changes,
+	})
+	if h.MaxSize > 0 && len(h.Undo) > h.MaxSize {
+		h.Undo = h.Undo[len(h.Undo)-h.MaxSize:]
+	}
+	h.Redo = nil
+}
+
+// applyChanges applies the changes in order, reverting the already applied
+// ones if any of them fails.
+func applyChanges(b *Board, changes []*Change) error {
+	for i, c := range changes {
+		if err := c.Apply(b); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				changes[j].Inverse().Apply(b)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Do applies the given changes to the board and records them as a single
+// action.
+func (h *History) Do(b *Board, description string, changes ...*Change) error {
+	if err := applyChanges(b, changes); err != nil {
+		return err
+	}
+	h.record(description, changes...)
+	return nil
+}
+
+// CanUndo returns true if there are actions to undo.
+func (h *History) CanUndo() bool {
+	return len(h.Undo) > 0
+}
+
+// CanRedo returns true if there are undone actions to redo.
+func (h *History) CanRedo() bool {
+	return len(h.Redo) > 0
+}
+
+// UndoLast reverts the last action performed on the board and returns it.
+func (h *History) UndoLast(b *Board) (*Action, error) {
+	if len(h.Undo) == 0 {
+		return nil, fmt.Errorf("nothing to undo")
+	}
+	a := h.Undo[len(h.Undo)-1]
+
+	inverse := make([]*Change, len(a.Changes))
+	for i, c := range a.Changes {
+		inverse[len(a.Changes)-1-i] = c.Inverse()
+	}
+	if err := applyChanges(b, inverse); err != nil {
+		return nil, fmt.Errorf("unable to undo %q: %v", a.Description, err)
+	}
+
+	h.Undo = h.Undo[:len(h.Undo)-1]
+	h.Redo = append(h.Redo, a)
+	return a, nil
+}
+
+// RedoLast performs again the last undone action and returns it.
+func (h *History) RedoLast(b *Board) (*Action, error) {
+	if len(h.Redo) == 0 {
+		return nil, fmt.Errorf("nothing to redo")
+	}
+	a := h.Redo[len(h.Redo)-1]
+
+	if err := applyChanges(b, a.Changes); err != nil {
+		return nil, fmt.Errorf("unable to redo %q: %v", a.Description, err)
+	}
+
+	h.Redo = h.Redo[:len(h.Redo)-1]
+	h.Undo = append(h.Undo, a)
+	return a, nil
+}
+
+// recordListMove records the move of a list that was previously at the given
+// index.
+func (h *History) recordListMove(b *Board, description string, list *List, from int) {
+	to := b.listIndex(list)
+	if from == to {
+		return
+	}
+	h.record(description, &Change{
+		Kind:      ChangeMoveList,
+		ListID:    list.ID,
+		FromIndex: from,
+		ToIndex:   to,
+	})
+}
+
+// MoveUp moves the list up (see Board.MoveUp), recording the change.
+func (h *History) MoveUp(b *Board, list *List) {
+	from := b.listIndex(list)
+	b.MoveUp(list)
+	h.recordListMove(b, "move list up", list, from)
+}
+
+// MoveDown moves the list down (see Board.MoveDown), recording the change.
+func (h *History) MoveDown(b *Board, list *List) {
+	from := b.listIndex(list)
+	b.MoveDown(list)
+	h.recordListMove(b, "move list down", list, from)
+}
+
+// recordTaskMove records the move of a task that was previously at the given
+// position.
+func (h *History) recordTaskMove(b *Board, description string, task *Task,
+	fromList *List, from int) {
+
+	toList, to := b.taskPosition(task)
+	if fromList == nil || toList == nil || (fromList == toList && from == to) {
+		return
+	}
+	h.record(description, &Change{
+		Kind:       ChangeMoveTask,
+		TaskID:     task.ID,
+		FromListID: fromList.ID,
+		FromIndex:  from,
+		ToListID:   toList.ID,
+		ToIndex:    to,
+	})
+}
+
+// MoveTaskUp moves the task up (see Board.MoveTaskUp), recording the change.
+func (h *History) MoveTaskUp(b *Board, task *Task) {
+	l, i := b.taskPosition(task)
+	b.MoveTaskUp(task)
+	h.recordTaskMove(b, "move task up", task, l, i)
+}
+
+// MoveTaskDown moves the task down (see Board.MoveTaskDown), recording the
+// change.
+func (h *History) MoveTaskDown(b *Board, task *Task) {
+	l, i := b.taskPosition(task)
+	b.MoveTaskDown(task)
+	h.recordTaskMove(b, "move task down", task, l, i)
+}
+
+// AppendNewTask adds a new task to the list (see Board.AppendNewTask),
+// recording the change.
+func (h *History) AppendNewTask(b *Board, list *List) *Task {
+	t := b.AppendNewTask(list)
+	h.record("add task", &Change{
+		Kind:     ChangeInsertTask,
+		ToListID: list.ID,
+		ToIndex:  len(list.Tasks) - 1,
+		Task:     t.clone(),
+	})
+	return t
+}
+
+// AppendNewList adds a new list to the end of the board (see
+// Board.AppendNewList), recording the change.
+func (h *History) AppendNewList(b *Board) *List {
+	l := b.AppendNewList()
+	h.record("add list", &Change{
+		Kind:    ChangeInsertList,
+		ToIndex: len(b.Lists) - 1,
+		List:    l.clone(),
+	})
+	return l
+}
+
+// PrependNewList adds a new list to the start of the board (see
+// Board.PrependNewList), recording the change.
+func (h *History) PrependNewList(b *Board) *List {
+	l := b.PrependNewList()
+	h.record("add list", &Change{
+		Kind:    ChangeInsertList,
+		ToIndex: 0,
+		List:    l.clone(),
+	})
+	return l
+}
+
+// EditList calls edit to modify the fields of the list (but not its tasks),
+// recording the change.
+func (h *History) EditList(b *Board, list *List, edit func(l *List)) {
+	old := list.fields()
+	edit(list)
+	h.record("edit list", &Change{
+		Kind:    ChangeEditList,
+		ListID:  list.ID,
+		OldList: old,
+		NewList: list.fields(),
+	})
+}
+
+// EditTask calls edit to modify the fields of the task (but not its times),
+// recording the change.
+func (h *History) EditTask(b *Board, task *Task, edit func(t *Task)) {
+	old := task.fields()
+	edit(task)
+	h.record("edit task", &Change{
+		Kind:    ChangeEditTask,
+		TaskID:  task.ID,
+		OldTask: old,
+		NewTask: task.fields(),
+	})
+}
+
+// AddTaskTime adds the time entry to the task (see Task.AddTaskTime),
+// recording the change.
+func (h *History) AddTaskTime(b *Board, task *Task, tt *TaskTime) {
+	task.AddTaskTime(tt)
+	ttc := *tt
+	h.record("add time", &Change{
+		Kind:     ChangeInsertTaskTime,
+		TaskID:   task.ID,
+		ToIndex:  len(task.Times) - 1,
+		TaskTime: &ttc,
+	})
+}
+
+// AddTaskTimes adds the time entries to the task, recording them as a single
+// action with the given description.
+func (h *History) AddTaskTimes(b *Board, description string, task *Task, tts ...*TaskTime) {
+	changes := make([]*Change, 0, len(tts))
+	for _, tt := range tts {
+		task.AddTaskTime(tt)
+		ttc := *tt
+		changes = append(changes, &Change{
+			Kind:     ChangeInsertTaskTime,
+			TaskID:   task.ID,
+			ToIndex:  len(task.Times) - 1,
+			TaskTime: &ttc,
+		})
+	}
+	h.record(description, changes...)
+}
+
+// TaskTimeAdded records a time entry that was already added to the task,
+// such as by stopping a work.
+func (h *History) TaskTimeAdded(b *Board, task *Task, tt *TaskTime) {
+	for i, other := range task.Times {
+		if other != tt {
+			continue
+		}
+		ttc := *tt
+		h.record("add time", &Change{
+			Kind:     ChangeInsertTaskTime,
+			TaskID:   task.ID,
+			ToIndex:  i,
+			TaskTime: &ttc,
+		})
+		return
+	}
+}
+
+// EditTaskTime calls edit to modify the time entry of the task, recording the
+// change.
+func (h *History) EditTaskTime(b *Board, task *Task, tt *TaskTime, edit func(tt *TaskTime)) {
+	old := *tt
+	edit(tt)
+	ttc := *tt
+	h.record("edit time", &Change{
+		Kind:        ChangeEditTaskTime,
+		TaskID:      task.ID,
+		OldTaskTime: &old,
+		NewTaskTime: &ttc,
+	})
+}
+
+// DeleteTaskTime removes the time entry from the task, recording the change.
+func (h *History) DeleteTaskTime(b *Board, task *Task, tt *TaskTime) {
+	i := task.timeIndex(tt)
+	if i < 0 {
+		return
+	}
+	task.Times = removeTaskTime(task.Times, i)
+	ttc := *tt
+	h.record("delete time", &Change{
+		Kind:      ChangeRemoveTaskTime,
+		TaskID:    task.ID,
+		FromIndex: i,
+		TaskTime:  &ttc,
+	})
+}
+
+// SplitTaskTime splits the time entry of the task at the given time (see
+// TaskTime.Split), adding the new entry right after it. Both changes are
+// recorded as a single action.
+func (h *History) SplitTaskTime(b *Board, task *Task, tt *TaskTime, at time.Time) (*TaskTime, error) {
+	i := task.timeIndex(tt)
+	if i < 0 {
+		return nil, fmt.Errorf("time entry is not in the task")
+	}
+	old := *tt
+	second, err := tt.Split(at)
+	if err != nil {
+		return nil, err
+	}
+	task.Times = insertTaskTime(task.Times, i+1, second)
+
+	first, secondc := *tt, *second
+	h.record("split time",
+		&Change{
+			Kind:        ChangeEditTaskTime,
+			TaskID:      task.ID,
+			OldTaskTime: &old,
+			NewTaskTime: &first,
+		},
+		&Change{
+			Kind:     ChangeInsertTaskTime,
+			TaskID:   task.ID,
+			ToIndex:  i + 1,
+			TaskTime: &secondc,
+		})
+	return second, nil
+}
+
+// MoveTaskTime moves the time entry from one task to the end of the times of
+// another, recording the change.
+func (h *History) MoveTaskTime(b *Board, from *Task, tt *TaskTime, to *Task) {
+	i := from.timeIndex(tt)
+	if i < 0 || from == to {
+		return
+	}
+	from.Times = removeTaskTime(from.Times, i)
+	to.Times = append(to.Times, tt)
+
+	ttc := *tt
+	h.record("move time",
+		&Change{
+			Kind:      ChangeRemoveTaskTime,
+			TaskID:    from.ID,
+			FromIndex: i,
+			TaskTime:  &ttc,
+		},
+		&Change{
+			Kind:     ChangeInsertTaskTime,
+			TaskID:   to.ID,
+			ToIndex:  len(to.Times) - 1,
+			TaskTime: &ttc,
+		})
+}
+
+// DeleteList removes the list from the board (see Board.DeleteList),
+// recording the change.
+func (h *History) DeleteList(b *Board, list *List) {
+	i := b.listIndex(list)
+	if i < 0 {
+		return
+	}
+	b.DeleteList(list)
+	h.record("delete list", &Change{
+		Kind:      ChangeRemoveList,
+		FromIndex: i,
+		List:      list.clone(),
+	})
+}
+
+// DeleteTask removes the task from the board (see Board.DeleteTask),
+// recording the change.
+func (h *History) DeleteTask(b *Board, task *Task) {
+	l, i := b.taskPosition(task)
+	if l == nil {
+		return
+	}
+	b.DeleteTask(task)
+	h.record("delete task", &Change{
+		Kind:       ChangeRemoveTask,
+		FromListID: l.ID,
+		FromIndex:  i,
+		Task:       task.clone(),
+	})
+}
+
+// DeleteArchivedTasks removes the archived tasks of the list from the board,
+// keeping the others, and records it as a single change. Returns the number of
+// deleted tasks.
+func (h *History) DeleteArchivedTasks(b *Board, list *List) int {
+	var changes []*Change
+	for i := len(list.Tasks) - 1; i >= 0; i-- {
+		task := list.Tasks[i]
+		if !task.Archived {
+			continue
+		}
+		b.DeleteTask(task)
+		changes = append(changes, &Change{
+			Kind:       ChangeRemoveTask,
+			FromListID: list.ID,
+			FromIndex:  i,
+			Task:       task.clone(),
+		})
+	}
+	h.record("delete archived tasks", changes...)
+	return len(changes)
+}
+
+// ArchiveList archives the list (see Board.ArchiveList), recording the
+// change.
+func (h *History) ArchiveList(b *Board, list *List) {
+	h.EditList(b, list, b.ArchiveList)
+	h.describeLast("archive list")
+}
+
+// RestoreList restores the archived list (see Board.RestoreList), recording
+// the change.
+func (h *History) RestoreList(b *Board, list *List) {
+	h.EditList(b, list, b.RestoreList)
+	h.describeLast("restore list")
+}
+
+// ArchiveTask archives the task (see Board.ArchiveTask), recording the
+// change.
+func (h *History) ArchiveTask(b *Board, task *Task) {
+	h.EditTask(b, task, b.ArchiveTask)
+	h.describeLast("archive task")
+}
+
+// RestoreTask restores the archived task (see Board.RestoreTask), recording
+// the change.
+func (h *History) RestoreTask(b *Board, task *Task) {
+	l := b.ListOfTask(task)
+	var oldList *List
+	if l != nil {
+		oldList = l.fields()
+	}
+
+	h.EditTask(b, task, b.RestoreTask)
+	h.describeLast("restore task")
+
+	// Restoring the task may also restore its list.
+	if l != nil && oldList.Archived != l.Archived {
+		a := h.Undo[len(h.Undo)-1]
+		a.Changes = append(a.Changes, &Change{
+			Kind:    ChangeEditList,
+			ListID:  l.ID,
+			OldList: oldList,
+			NewList: l.fields(),
+		})
+	}
+}
+
+// describeLast changes the description of the last recorded action.
+func (h *History) describeLast(description string) {
+	if len(h.Undo) > 0 {
+		h.Undo[len(h.Undo)-1].Description = description
+	}
+}
+
+// HistoryFilename returns the name of the file that stores the undo history
+// of the given board file.
+func HistoryFilename(boardFilename string) string {
+	return boardFilename + ".history.yml"
+}
+
+// HistoryToFile atomically saves the history to the given file.
+func HistoryToFile(filename string, h *History) error {
+	return writeFileAtomic(filename, func(w io.Writer) error {
+		enc := yaml.NewEncoder(w)
+		if err := enc.Encode(h); err != nil {
+			return err
+		}
+		return enc.Close()
+	})
+}
+
+// HistoryFromFile loads the history of the given board from a file. A new,
+// empty history is returned if the file doesn't exist or if it stores the
+// history of some other board.
+func HistoryFromFile(filename string, b *Board) (*History, error) {
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return NewHistory(b), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := NewHistory(b)
+	err = yaml.NewDecoder(f).Decode(h)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("error decoding file %s: %v", filename, err)
+	}
+	if h.BoardID != b.ID {
+		return NewHistory(b), nil
+	}
+	return h, nil
+}
@@ -0,0 +1,210 @@
+package nonota
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// boardSnapshot returns a serialized version of the board, used to compare
+// boards before and after undoing actions.
+func boardSnapshot(t *testing.T, b *Board) string {
+	t.Helper()
+	data, err := yaml.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestHistoryUndoRedo(t *testing.T) {
+	b := &Board{ID: NewID()}
+	h := NewHistory(b)
+
+	type step struct {
+		name string
+		do   func()
+	}
+
+	var l1, l2 *List
+	var t1, t2 *Task
+	tt := &TaskTime{
+		Start:    time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC),
+		End:      time.Date(2019, 3, 1, 11, 0, 0, 0, time.UTC),
+		Duration: time.Hour,
+	}
+	steps := []step{
+		{"append list", func() { l1 = h.AppendNewList(b) }},
+		{"prepend list", func() { l2 = h.PrependNewList(b) }},
+		{"append task", func() { t1 = h.AppendNewTask(b, l1) }},
+		{"append second task", func() { t2 = h.AppendNewTask(b, l1) }},
+		{"move list down", func() { h.MoveDown(b, l2) }},
+		{"move task up", func() { h.MoveTaskUp(b, t2) }},
+		{"move task to other list", func() { h.MoveTaskDown(b, t1) }},
+		{"move task back", func() { h.MoveTaskUp(b, t1) }},
+		{"edit task", func() {
+			h.EditTask(b, t1, func(t *Task) {
+				t.Title = "edited"
+				t.Description = "descr"
+			})
+		}},
+		{"edit list", func() {
+			h.EditList(b, l1, func(l *List) { l.Title = "edited" })
+		}},
+		{"add time", func() { h.AddTaskTime(b, t1, tt) }},
+		{"edit time", func() {
+			h.EditTaskTime(b, t1, tt, func(tt *TaskTime) {
+				tt.Note = "note"
+				tt.Duration = 40 * time.Minute
+			})
+		}},
+		{"split time", func() {
+			at := time.Date(2019, 3, 1, 10, 30, 0, 0, time.UTC)
+			if _, err := h.SplitTaskTime(b, t1, tt, at); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"move time", func() { h.MoveTaskTime(b, t1, tt, t2) }},
+		{"delete time", func() { h.DeleteTaskTime(b, t2, tt) }},
+		{"archive task", func() { h.ArchiveTask(b, t1) }},
+		{"archive list", func() { h.ArchiveList(b, l1) }},
+		{"restore task", func() { h.RestoreTask(b, t1) }},
+		{"archive second task", func() { h.ArchiveTask(b, t2) }},
+		{"delete archived tasks", func() {
+			if n := h.DeleteArchivedTasks(b, b.ListOfTask(t2)); n != 1 {
+				t.Fatalf("unexpected number of deleted tasks %d", n)
+			}
+		}},
+		{"delete task", func() { h.DeleteTask(b, t1) }},
+		{"delete list", func() { h.DeleteList(b, l1) }},
+	}
+
+	// Perform every step, keeping the board state before and after each
+	// one.
+	snapshots := []string{boardSnapshot(t, b)}
+	for _, s := range steps {
+		s.do()
+		snap := boardSnapshot(t, b)
+		if snap == snapshots[len(snapshots)-1] {
+			t.Fatalf("step %q did not change the board", s.name)
+		}
+		snapshots = append(snapshots, snap)
+	}
+	if len(h.Undo) != len(steps) {
+		t.Fatalf("unexpected number of recorded actions: %d", len(h.Undo))
+	}
+
+	// Undo everything, checking the board goes back through every state.
+	for i := len(steps) - 1; i >= 0; i-- {
+		if _, err := h.UndoLast(b); err != nil {
+			t.Fatalf("undo %q: %v", steps[i].name, err)
+		}
+		if snap := boardSnapshot(t, b); snap != snapshots[i] {
+			t.Fatalf("undo %q: unexpected board\n%s\nwant\n%s",
+				steps[i].name, snap, snapshots[i])
+		}
+	}
+	if _, err := h.UndoLast(b); err == nil {
+		t.Fatalf("expected error undoing an empty history")
+	}
+
+	// Redo everything.
+	for i := range steps {
+		if _, err := h.RedoLast(b); err != nil {
+			t.Fatalf("redo %q: %v", steps[i].name, err)
+		}
+		if snap := boardSnapshot(t, b); snap != snapshots[i+1] {
+			t.Fatalf("redo %q: unexpected board\n%s\nwant\n%s",
+				steps[i].name, snap, snapshots[i+1])
+		}
+	}
+
+	// Performing a new action discards the redo history.
+	h.UndoLast(b)
+	h.AppendNewList(b)
+	if h.CanRedo() {
+		t.Fatalf("redo history not discarded after new action")
+	}
+}
+
+func TestDeleteArchivedTasks(t *testing.T) {
+	b := &Board{ID: NewID()}
+	h := NewHistory(b)
+	l := h.AppendNewList(b)
+	t1, t2, t3 := h.AppendNewTask(b, l), h.AppendNewTask(b, l), h.AppendNewTask(b, l)
+	h.AddTaskTime(b, t2, &TaskTime{Duration: time.Hour})
+	h.ArchiveTask(b, t1)
+	h.ArchiveTask(b, t3)
+	before := boardSnapshot(t, b)
+	undo := len(h.Undo)
+
+	// Only the archived tasks are deleted, in a single action.
+	if n := h.DeleteArchivedTasks(b, l); n != 2 {
+		t.Fatalf("unexpected number of deleted tasks %d", n)
+	}
+	if len(l.Tasks) != 1 || l.Tasks[0] != t2 || len(t2.Times) != 1 {
+		t.Fatalf("unexpected tasks after deleting %v", l.Tasks)
+	}
+	if len(h.Undo) != undo+1 {
+		t.Fatalf("unexpected number of recorded actions %d", len(h.Undo))
+	}
+	if _, err := h.UndoLast(b); err != nil {
+		t.Fatal(err)
+	}
+	if snap := boardSnapshot(t, b); snap != before {
+		t.Fatalf("unexpected board after undo\n%s\nwant\n%s", snap, before)
+	}
+
+	// Nothing is recorded for lists without archived tasks.
+	h.RestoreTask(b, b.TaskByID(t1.ID))
+	h.RestoreTask(b, b.TaskByID(t3.ID))
+	undo = len(h.Undo)
+	if n := h.DeleteArchivedTasks(b, b.Lists[0]); n != 0 || len(h.Undo) != undo {
+		t.Fatalf("unexpected deletion of %d tasks", n)
+	}
+}
+
+func TestHistoryPersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nonota")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b := &Board{ID: NewID()}
+	h := NewHistory(b)
+	l := h.AppendNewList(b)
+	task := h.AppendNewTask(b, l)
+	h.EditTask(b, task, func(t *Task) { t.Title = "edited" })
+
+	fname := filepath.Join(dir, "history.yml")
+	if err := HistoryToFile(fname, h); err != nil {
+		t.Fatal(err)
+	}
+
+	// The history of a different board is ignored.
+	other, err := HistoryFromFile(fname, &Board{ID: NewID()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.CanUndo() {
+		t.Fatalf("history of another board was loaded")
+	}
+
+	loaded, err := HistoryFromFile(fname, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := loaded.UndoLast(b); err != nil {
+			t.Fatalf("undo %d: %v", i, err)
+		}
+	}
+	if len(b.Lists) != 0 {
+		t.Fatalf("board not empty after undoing everything")
+	}
+}
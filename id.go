@@ -0,0 +1,46 @@
+package nonota
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+// idSize is the number of random bytes used when generating new ids. 128
+// bits is plenty to avoid collisions between entities of a board (or even
+// between boards).
+const idSize = 16
+
+// NewID returns a new random id, suitable to identify any entity of a board
+// (lists, tasks, time entries, etc).
+func NewID() string {
+	var b [idSize]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Errorf("unable to read random bytes for id: %v", err))
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// ensureIDs fills the id of every entity of the board that doesn't have one
+// yet. Returns true if any id was assigned.
+func (b *Board) ensureIDs() bool {
+	changed := false
+	fill := func(id *string) {
+		if *id == "" {
+			*id = NewID()
+			changed = true
+		}
+	}
+
+	fill(&b.ID)
+	for _, l := range b.Lists {
+		fill(&l.ID)
+		for _, t := range l.Tasks {
+			fill(&t.ID)
+			for _, tt := range t.Times {
+				fill(&tt.ID)
+			}
+		}
+	}
+	return changed
+}
@@ -0,0 +1,112 @@
+package nonota
+
+import (
+	"time"
+)
+
+const (
+	// DefaultIdleThreshold is how long without any activity before the user
+	// is considered idle, unless configured otherwise in the board.
+	DefaultIdleThreshold = 15 * time.Minute
+
+	// SuspendGap is the minimum gap between two ticks of an IdleDetector
+	// for the computer to be considered suspended between them.
+	SuspendGap = time.Minute
+)
+
+// IdlePeriod is an interval during which the user was away.
+type IdlePeriod struct {
+	Start time.Time
+	End   time.Time
+
+	// Suspended is true if the computer was suspended during the period.
+	Suspended bool
+}
+
+// Duration returns the duration of the idle period.
+func (p IdlePeriod) Duration() time.Duration {
+	return p.End.Sub(p.Start)
+}
+
+// IdleDetector detects when the user has been away, either because there was
+// no activity for longer than a threshold or because the computer was
+// suspended.
+//
+// Activity must be called on every user interaction and Tick must be called
+// periodically (at intervals much shorter than SuspendGap). It is not safe for
+// concurrent use.
+type IdleDetector struct {
+	clock     Clock
+	threshold time.Duration
+
+	lastActivity time.Time
+	lastTick     time.Time
+
+	idle      bool
+	idleStart time.Time
+	suspended bool
+}
+
+// NewIdleDetector returns a detector that considers the user idle after the
+// given threshold without activity. A threshold <= 0 only detects suspends.
+// A nil clock means the system clock.
+func NewIdleDetector(clock Clock, threshold time.Duration) *IdleDetector {
+	clock = clockOrSystem(clock)
+	now := clock.Now()
+	return &IdleDetector{
+		clock:        clock,
+		threshold:    threshold,
+		lastActivity: now,
+		lastTick:     now,
+	}
+}
+
+// markIdle flags the user as idle since the given time.
+func (d *IdleDetector) markIdle(since time.Time, suspended bool) {
+	if !d.idle || since.Before(d.idleStart) {
+		d.idleStart = since
+	}
+	d.idle = true
+	d.suspended = d.suspended || suspended
+}
+
+// Tick checks whether the user became idle. A gap since the previous tick
+// longer than SuspendGap means the computer was suspended (or the process
+// stopped) during that time, which is always considered idle.
+func (d *IdleDetector) Tick() {
+	now := d.clock.Now()
+	if now.Sub(d.lastTick) >= SuspendGap {
+		d.markIdle(d.lastTick, true)
+	}
+	if d.threshold > 0 && now.Sub(d.lastActivity) >= d.threshold {
+		d.markIdle(d.lastActivity, false)
+	}
+	d.lastTick = now
+}
+
+// Idle returns true if the user is currently considered idle.
+func (d *IdleDetector) Idle() bool {
+	return d.idle
+}
+
+// Activity records a user interaction. If the user was idle, the period
+// during which they were away is returned along with true.
+func (d *IdleDetector) Activity() (IdlePeriod, bool) {
+	// Catch suspends that happened since the last tick.
+	d.Tick()
+
+	now := d.clock.Now()
+	d.lastActivity = now
+	if !d.idle {
+		return IdlePeriod{}, false
+	}
+
+	period := IdlePeriod{
+		Start:     d.idleStart,
+		End:       now,
+		Suspended: d.suspended,
+	}
+	d.idle = false
+	d.suspended = false
+	return period, true
+}
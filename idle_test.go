@@ -0,0 +1,108 @@
+package nonota
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIdleDetector(t *testing.T) {
+	start := time.Date(2019, 3, 20, 10, 0, 0, 0, time.Local)
+	clock := NewManualClock(start)
+	d := NewIdleDetector(clock, 10*time.Minute)
+
+	// tick advances the clock by the given duration, one second at a time.
+	tick := func(dur time.Duration) {
+		for i := time.Duration(0); i < dur; i += time.Second {
+			clock.Advance(time.Second)
+			d.Tick()
+		}
+	}
+
+	// Activity before the threshold is not idle.
+	tick(9 * time.Minute)
+	if _, idle := d.Activity(); idle || d.Idle() {
+		t.Fatalf("unexpected idle period")
+	}
+
+	// No activity for longer than the threshold.
+	lastActivity := clock.Now()
+	tick(12 * time.Minute)
+	if !d.Idle() {
+		t.Fatalf("expected to be idle")
+	}
+	period, idle := d.Activity()
+	if !idle {
+		t.Fatalf("expected an idle period")
+	}
+	if !period.Start.Equal(lastActivity) || !period.End.Equal(clock.Now()) ||
+		period.Duration() != 12*time.Minute || period.Suspended {
+		t.Fatalf("unexpected idle period %#v", period)
+	}
+	if _, idle := d.Activity(); idle {
+		t.Fatalf("idle period returned twice")
+	}
+
+	// A suspend shorter than the threshold is still idle, starting when
+	// the computer was suspended.
+	tick(time.Minute)
+	suspendedAt := clock.Now()
+	clock.Advance(5 * time.Minute)
+	tick(time.Second)
+	period, idle = d.Activity()
+	if !idle || !period.Suspended || !period.Start.Equal(suspendedAt) ||
+		period.Duration() != 5*time.Minute+time.Second {
+		t.Fatalf("unexpected suspend period %#v", period)
+	}
+
+	// Suspends are detected on activity even before the next tick.
+	tick(time.Minute)
+	suspendedAt = clock.Now()
+	clock.Advance(2 * time.Minute)
+	period, idle = d.Activity()
+	if !idle || !period.Suspended || !period.Start.Equal(suspendedAt) {
+		t.Fatalf("unexpected suspend period %#v", period)
+	}
+
+	// An idle period that includes a suspend starts at the last activity.
+	lastActivity = clock.Now()
+	tick(11 * time.Minute)
+	clock.Advance(time.Hour)
+	period, idle = d.Activity()
+	if !idle || !period.Suspended || !period.Start.Equal(lastActivity) {
+		t.Fatalf("unexpected idle period %#v", period)
+	}
+
+	// Without a threshold, only suspends are detected.
+	d = NewIdleDetector(clock, -1)
+	tick(time.Hour)
+	if _, idle := d.Activity(); idle {
+		t.Fatalf("unexpected idle period without a threshold")
+	}
+}
+
+func TestWorkDiscard(t *testing.T) {
+	clock := NewManualClock(time.Date(2019, 3, 20, 10, 0, 0, 0, time.Local))
+	w := StartWorkWithClock(&Task{}, clock)
+	clock.Advance(30 * time.Minute)
+
+	if d := w.Discard(20 * time.Minute); d != 20*time.Minute {
+		t.Fatalf("unexpected discarded duration %s", d)
+	}
+	if d := w.CurrentDuration(); d != 11*time.Minute {
+		t.Fatalf("unexpected duration %s", d)
+	}
+
+	// The work keeps running after discarding.
+	clock.Advance(time.Minute)
+	if d := w.CurrentDuration(); d != 12*time.Minute {
+		t.Fatalf("unexpected duration %s", d)
+	}
+
+	// The duration never becomes negative.
+	if d := w.Discard(time.Hour); d != 12*time.Minute {
+		t.Fatalf("unexpected discarded duration %s", d)
+	}
+	if d := w.CurrentDuration(); d != 0 {
+		t.Fatalf("unexpected duration %s", d)
+	}
+}
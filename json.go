@@ -0,0 +1,233 @@
+package nonota
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// JSONFormat is the value of the "format" member of json boards.
+const JSONFormat = "nonota-board"
+
+// JSONVersion is the version of the json board format written by this version
+// of nonota. It's independent of SchemaVersion: it only needs to be increased
+// when the json format changes in a way that older readers would misread.
+// The format is documented in the README.
+const JSONVersion = 1
+
+// jsonBoard and the types below define the json format of boards. They are
+// kept separate from the board types so that the format only changes on
+// purpose.
+type jsonBoard struct {
+	Format   string       `json:"format"`
+	Version  int          `json:"version"`
+	ID       string       `json:"id"`
+	Settings jsonSettings `json:"settings"`
+	Lists    []jsonList   `json:"lists"`
+}
+
+type jsonSettings struct {
+	Billing         jsonBilling  `json:"billing"`
+	WeekStart       string       `json:"week_start,omitempty"`
+	TimeZone        string       `json:"time_zone,omitempty"`
+	IdleThresholdNs int64        `json:"idle_threshold_ns,omitempty"`
+	Pomodoro        jsonPomodoro `json:"pomodoro"`
+}
+
+type jsonBilling struct {
+	Kind     string `json:"kind,omitempty"`
+	StartDay int    `json:"start_day,omitempty"`
+	Anchor   string `json:"anchor,omitempty"`
+}
+
+type jsonPomodoro struct {
+	WorkNs         int64 `json:"work_ns,omitempty"`
+	ShortBreakNs   int64 `json:"short_break_ns,omitempty"`
+	LongBreakNs    int64 `json:"long_break_ns,omitempty"`
+	LongBreakEvery int   `json:"long_break_every,omitempty"`
+}
+
+type jsonList struct {
+	ID       string     `json:"id"`
+	Title    string     `json:"title"`
+	Archived bool       `json:"archived,omitempty"`
+	Tasks    []jsonTask `json:"tasks"`
+}
+
+type jsonTask struct {
+	ID          string         `json:"id"`
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	Tags        *[]string      `json:"tags"`
+	Archived    bool           `json:"archived,omitempty"`
+	Times       []jsonTaskTime `json:"times"`
+}
+
+type jsonTaskTime struct {
+	ID         string    `json:"id"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	DurationNs int64     `json:"duration_ns"`
+	Note       string    `json:"note,omitempty"`
+	Pomodoros  int       `json:"pomodoros,omitempty"`
+}
+
+// BoardToJSON writes the board to w in the json format.
+func BoardToJSON(w io.Writer, b *Board) error {
+	s := b.Settings
+	jb := jsonBoard{
+		Format:  JSONFormat,
+		Version: JSONVersion,
+		ID:      b.ID,
+		Settings: jsonSettings{
+			Billing: jsonBilling{
+				Kind:     s.Billing.Kind,
+				StartDay: s.Billing.StartDay,
+				Anchor:   s.Billing.Anchor,
+			},
+			TimeZone:        s.TimeZone,
+			IdleThresholdNs: int64(s.IdleThreshold),
+			Pomodoro: jsonPomodoro{
+				WorkNs:         int64(s.Pomodoro.Work),
+				ShortBreakNs:   int64(s.Pomodoro.ShortBreak),
+				LongBreakNs:    int64(s.Pomodoro.LongBreak),
+				LongBreakEvery: s.Pomodoro.LongBreakEvery,
+			},
+		},
+		Lists: make([]jsonList, 0, len(b.Lists)),
+	}
+	if s.WeekStart != 0 {
+		jb.Settings.WeekStart = strings.ToLower(time.Weekday(s.WeekStart).String())
+	}
+
+	for _, l := range b.Lists {
+		jl := jsonList{
+			ID:       l.ID,
+			Title:    l.Title,
+			Archived: l.Archived,
+			Tasks:    make([]jsonTask, 0, len(l.Tasks)),
+		}
+		for _, t := range l.Tasks {
+			// Tags are always written (even if empty), given missing
+			// tags are parsed from the title when reading.
+			tags := t.Tags
+			if tags == nil {
+				tags = []string{}
+			}
+			jt := jsonTask{
+				ID:          t.ID,
+				Title:       t.Title,
+				Description: t.Description,
+				Tags:        &tags,
+				Archived:    t.Archived,
+				Times:       make([]jsonTaskTime, 0, len(t.Times)),
+			}
+			for _, tt := range t.Times {
+				jt.Times = append(jt.Times, jsonTaskTime{
+					ID:         tt.ID,
+					Start:      tt.Start,
+					End:        tt.End,
+					DurationNs: int64(tt.Duration),
+					Note:       tt.Note,
+					Pomodoros:  tt.Pomodoros,
+				})
+			}
+			jl.Tasks = append(jl.Tasks, jt)
+		}
+		jb.Lists = append(jb.Lists, jl)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(jb)
+}
+
+// BoardFromJSON reads a board in the json format from r.
+//
+// Boards generated by other tools may leave out the ids (which are then
+// assigned) and the tags of tasks (which are then parsed from their titles).
+func BoardFromJSON(r io.Reader) (*Board, error) {
+	var jb jsonBoard
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&jb); err != nil {
+		return nil, fmt.Errorf("error decoding json board: %v", err)
+	}
+	if jb.Format != JSONFormat {
+		return nil, fmt.Errorf("not a json board (format %q)", jb.Format)
+	}
+	if jb.Version < 1 || jb.Version > JSONVersion {
+		return nil, fmt.Errorf("unsupported json board version %d", jb.Version)
+	}
+
+	js := jb.Settings
+	b := &Board{
+		Version: SchemaVersion,
+		ID:      jb.ID,
+		Settings: Settings{
+			Billing: BillingPeriod{
+				Kind:     js.Billing.Kind,
+				StartDay: js.Billing.StartDay,
+				Anchor:   js.Billing.Anchor,
+			},
+			TimeZone:      js.TimeZone,
+			IdleThreshold: time.Duration(js.IdleThresholdNs),
+			Pomodoro: PomodoroSettings{
+				Work:           time.Duration(js.Pomodoro.WorkNs),
+				ShortBreak:     time.Duration(js.Pomodoro.ShortBreakNs),
+				LongBreak:      time.Duration(js.Pomodoro.LongBreakNs),
+				LongBreakEvery: js.Pomodoro.LongBreakEvery,
+			},
+		},
+	}
+	if js.WeekStart != "" {
+		found := false
+		for wd := time.Sunday; wd <= time.Saturday; wd++ {
+			if strings.EqualFold(js.WeekStart, wd.String()) {
+				b.Settings.WeekStart = Weekday(wd)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("invalid week start %q", js.WeekStart)
+		}
+	}
+	if err := b.Settings.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid settings: %v", err)
+	}
+
+	for _, jl := range jb.Lists {
+		l := &List{ID: jl.ID, Title: jl.Title, Archived: jl.Archived}
+		for _, jt := range jl.Tasks {
+			t := &Task{
+				ID:          jt.ID,
+				Title:       jt.Title,
+				Description: jt.Description,
+				Archived:    jt.Archived,
+			}
+			switch {
+			case jt.Tags == nil:
+				t.Tags = ParseTags(t.Title)
+			case len(*jt.Tags) > 0:
+				t.Tags = *jt.Tags
+			}
+			for _, jtt := range jt.Times {
+				t.Times = append(t.Times, &TaskTime{
+					ID:        jtt.ID,
+					Start:     jtt.Start,
+					End:       jtt.End,
+					Duration:  time.Duration(jtt.DurationNs),
+					Note:      jtt.Note,
+					Pomodoros: jtt.Pomodoros,
+				})
+			}
+			l.Tasks = append(l.Tasks, t)
+		}
+		b.Lists = append(b.Lists, l)
+	}
+	b.ensureIDs()
+
+	return b, nil
+}
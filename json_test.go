@@ -0,0 +1,141 @@
+package nonota
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBoardJSONRoundTrip(t *testing.T) {
+	brt := time.FixedZone("", -3*60*60)
+	start := time.Date(2019, 3, 1, 10, 0, 0, 500, brt)
+	b := &Board{
+		Version: SchemaVersion,
+		ID:      NewID(),
+		Settings: Settings{
+			Billing:       BillingPeriod{Kind: BillingBiWeekly, Anchor: "2019-01-07"},
+			WeekStart:     Weekday(time.Monday),
+			TimeZone:      "America/Sao_Paulo",
+			IdleThreshold: 30 * time.Minute,
+			Pomodoro:      PomodoroSettings{Work: 50 * time.Minute, LongBreakEvery: 2},
+		},
+		Lists: []*List{{
+			ID:    NewID(),
+			Title: "Backlog",
+			Tasks: []*Task{{
+				ID:          NewID(),
+				Title:       "First #one",
+				Description: "Some\n\"description\"",
+				Tags:        []string{"one"},
+				Times: []*TaskTime{{
+					ID:        NewID(),
+					Start:     start,
+					End:       start.Add(time.Hour),
+					Note:      "note",
+					Duration:  50*time.Minute + time.Nanosecond,
+					Pomodoros: 2,
+				}},
+			}, {
+				ID:       NewID(),
+				Title:    "Second",
+				Archived: true,
+			}},
+		}, {
+			ID:       NewID(),
+			Title:    "Done",
+			Archived: true,
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := BoardToJSON(&buf, b); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := BoardFromJSON(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if boardSnapshot(t, b) != boardSnapshot(t, loaded) {
+		t.Fatalf("board changed after json round trip:\n%s\n%s",
+			boardSnapshot(t, b), boardSnapshot(t, loaded))
+	}
+	if !loaded.Lists[0].Tasks[0].Times[0].Start.Equal(start) {
+		t.Fatalf("start time changed after json round trip")
+	}
+}
+
+func TestBoardJSONClearedTags(t *testing.T) {
+	// Tasks whose tags were removed keep having no tags, even if their
+	// titles have #tag tokens.
+	b := &Board{
+		Version: SchemaVersion,
+		ID:      NewID(),
+		Lists: []*List{{
+			ID:    NewID(),
+			Title: "Backlog",
+			Tasks: []*Task{{
+				ID:    NewID(),
+				Title: "Task #tag",
+			}},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := BoardToJSON(&buf, b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"tags": []`) {
+		t.Fatalf("empty tags not written:\n%s", buf.String())
+	}
+	loaded, err := BoardFromJSON(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags := loaded.Lists[0].Tasks[0].Tags; len(tags) != 0 {
+		t.Fatalf("unexpected tags after json round trip %v", tags)
+	}
+	if boardSnapshot(t, b) != boardSnapshot(t, loaded) {
+		t.Fatalf("board changed after json round trip:\n%s\n%s",
+			boardSnapshot(t, b), boardSnapshot(t, loaded))
+	}
+}
+
+func TestBoardFromJSON(t *testing.T) {
+	// Boards generated by other tools may omit ids and tags.
+	src := `{
+		"format": "nonota-board",
+		"version": 1,
+		"settings": {"week_start": "monday", "billing": {}, "pomodoro": {}},
+		"lists": [{"title": "List", "tasks": [{"title": "Task #tag", "times": [
+			{"start": "2019-03-01T10:00:00Z", "end": "2019-03-01T11:00:00Z",
+			 "duration_ns": 3600000000000}
+		]}]}]
+	}`
+	b, err := BoardFromJSON(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := b.Lists[0].Tasks[0]
+	if b.ID == "" || b.Lists[0].ID == "" || task.ID == "" || task.Times[0].ID == "" {
+		t.Fatalf("ids were not assigned")
+	}
+	if !task.HasTag("tag") || task.Times[0].Duration != time.Hour {
+		t.Fatalf("unexpected task %#v", task)
+	}
+	if b.Settings.WeekStart != Weekday(time.Monday) {
+		t.Fatalf("unexpected week start %d", b.Settings.WeekStart)
+	}
+
+	bad := []string{
+		`{"format": "other", "version": 1}`,
+		`{"format": "nonota-board", "version": 2}`,
+		`{"format": "nonota-board", "version": 1, "unknown": true}`,
+		`{"format": "nonota-board", "version": 1, "settings": {"week_start": "someday"}}`,
+	}
+	for _, src := range bad {
+		if _, err := BoardFromJSON(strings.NewReader(src)); err == nil {
+			t.Fatalf("expected error decoding %s", src)
+		}
+	}
+}
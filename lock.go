@@ -0,0 +1,77 @@
+package nonota
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrLocked is returned when trying to acquire a lock without waiting and the
+// lock is held by some other process.
+var ErrLocked = errors.New("file is locked by another process")
+
+// FileLock is an advisory lock held on a file. Locks are only advisory: they
+// are respected by nonota processes but don't prevent other programs from
+// modifying the locked files.
+type FileLock struct {
+	f *os.File
+}
+
+// lockFile acquires a lock on the given path, creating the file if needed
+// (and create is true). Exclusive locks may only be held by a single process,
+// while shared locks may be held by any number of processes (as long as no
+// exclusive lock is held). When wait is false, ErrLocked is returned instead
+// of waiting for the lock to be released.
+func lockFile(path string, exclusive, wait, create bool) (*FileLock, error) {
+	flag := os.O_RDONLY
+	if create {
+		flag = os.O_RDWR | os.O_CREATE
+	}
+	f, err := os.OpenFile(path, flag, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockFd(f, exclusive, wait); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &FileLock{f: f}, nil
+}
+
+// Unlock releases the lock.
+func (l *FileLock) Unlock() error {
+	if l == nil || l.f == nil {
+		return nil
+	}
+	err := unlockFd(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	l.f = nil
+	return err
+}
+
+// LockBoard acquires the lock used to serialize reads and writes of the given
+// board file, waiting for it if needed. Writers should request an exclusive
+// lock while readers should request a shared one.
+//
+// A separate lock file is used (instead of locking the board file itself)
+// because saving a board replaces the file. The lock file is only created by
+// writers, so that reading a board never requires write access to its dir.
+// Until a writer creates it there's nothing to synchronize with, given that
+// boards are always replaced atomically.
+func LockBoard(filename string, exclusive bool) (*FileLock, error) {
+	l, err := lockFile(filename+".lock", exclusive, true, exclusive)
+	if !exclusive && os.IsNotExist(err) {
+		return &FileLock{}, nil
+	}
+	return l, err
+}
+
+// LockInstance tries to acquire the lock that signals an interactive nonota
+// instance is editing the given board file. It returns ErrLocked if another
+// instance already holds it.
+func LockInstance(filename string) (*FileLock, error) {
+	return lockFile(filename+".ui.lock", true, false, true)
+}
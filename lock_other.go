@@ -0,0 +1,19 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package nonota
+
+import (
+	"os"
+)
+
+// File locking is not supported on this platform, so locks are always
+// acquired.
+
+func lockFd(f *os.File, exclusive, wait bool) error {
+	return nil
+}
+
+func unlockFd(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,151 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package nonota
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockInstance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nonota")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fname := filepath.Join(dir, "board.yml")
+
+	l, err := LockInstance(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LockInstance(fname); err != ErrLocked {
+		t.Fatalf("unexpected error locking a second instance: %v", err)
+	}
+	if err := l.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Unlock(); err != nil {
+		t.Fatalf("unexpected error unlocking twice: %v", err)
+	}
+
+	l, err = LockInstance(fname)
+	if err != nil {
+		t.Fatalf("unable to lock after unlocking: %v", err)
+	}
+	l.Unlock()
+}
+
+func TestLockBoard(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nonota")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fname := filepath.Join(dir, "board.yml")
+	lockName := fname + ".lock"
+
+	// Readers don't create the lock file.
+	shared, err := LockBoard(fname, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(lockName); !os.IsNotExist(err) {
+		t.Fatalf("lock file created by a reader: %v", err)
+	}
+	shared.Unlock()
+
+	// Writers do, and exclude other writers and readers until done.
+	exclusive, err := LockBoard(fname, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(lockName); err != nil {
+		t.Fatalf("lock file not created by a writer: %v", err)
+	}
+	waitLock := func(exclusive bool) chan error {
+		done := make(chan error, 1)
+		go func() {
+			l, err := LockBoard(fname, exclusive)
+			if err == nil {
+				err = l.Unlock()
+			}
+			done <- err
+		}()
+		return done
+	}
+	checkBlocked := func(done chan error, what string) {
+		t.Helper()
+		select {
+		case err := <-done:
+			t.Fatalf("%s did not wait for the lock (%v)", what, err)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	checkAcquired := func(done chan error, what string) {
+		t.Helper()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("%s: %v", what, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s did not acquire the released lock", what)
+		}
+	}
+	reader, writer := waitLock(false), waitLock(true)
+	checkBlocked(reader, "reader")
+	checkBlocked(writer, "writer")
+	exclusive.Unlock()
+	checkAcquired(reader, "reader")
+	checkAcquired(writer, "writer")
+
+	// Readers share the lock, but writers wait for them.
+	r1, err := LockBoard(fname, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := LockBoard(fname, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer = waitLock(true)
+	checkBlocked(writer, "writer")
+	r1.Unlock()
+	checkBlocked(writer, "writer")
+	r2.Unlock()
+	checkAcquired(writer, "writer")
+}
+
+func TestBoardFileLocking(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nonota")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fname := filepath.Join(dir, "board.yml")
+	if err := BoardToFile(fname, &Board{ID: NewID()}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Saving waits for the readers of the board.
+	l, err := LockBoard(fname, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- BoardToFile(fname, &Board{ID: NewID()}) }()
+	select {
+	case err := <-done:
+		t.Fatalf("board saved while locked (%v)", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	l.Unlock()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
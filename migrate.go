@@ -0,0 +1,144 @@
+package nonota
+
+import (
+	"fmt"
+)
+
+// SchemaVersion is the version of the board file format written by this
+// version of nonota. It must be increased (and a new migration registered)
+// whenever a change to the format requires older files to be converted.
+const SchemaVersion = 2
+
+// yamlDoc is the generic representation of a yaml document as decoded by the
+// yaml package, used by migrations to operate on the raw board file.
+type yamlDoc = map[interface{}]interface{}
+
+// migration upgrades a raw board document from version from to version
+// from+1.
+type migration struct {
+	from        int
+	description string
+	migrate     func(doc yamlDoc) error
+}
+
+// migrations is the registry of all migrations, sorted by the version they
+// upgrade from.
+var migrations = []migration{
+	{0, "assign ids to lists, tasks and time entries", migrateV0AssignIDs},
+	{1, "parse tags from task titles", migrateV1ParseTags},
+}
+
+func init() {
+	if len(migrations) != SchemaVersion {
+		panic("number of migrations does not match the schema version")
+	}
+	for i, m := range migrations {
+		if m.from != i {
+			panic(fmt.Errorf("migration %d upgrades from version %d", i, m.from))
+		}
+	}
+}
+
+// docVersion returns the schema version of the given raw board document.
+// Boards from before versioning was introduced do not have one and are
+// considered version 0.
+func docVersion(doc yamlDoc) (int, error) {
+	v, has := doc["version"]
+	if !has {
+		return 0, nil
+	}
+	version, ok := v.(int)
+	if !ok || version < 0 {
+		return 0, fmt.Errorf("invalid board version %v", v)
+	}
+	return version, nil
+}
+
+// migrateDoc upgrades the given raw board document to the current schema
+// version, one version at a time.
+func migrateDoc(doc yamlDoc) error {
+	version, err := docVersion(doc)
+	if err != nil {
+		return err
+	}
+	if version > SchemaVersion {
+		return fmt.Errorf("board version %d is newer than the latest "+
+			"supported version %d; upgrade nonota to open it", version,
+			SchemaVersion)
+	}
+
+	for ; version < SchemaVersion; version++ {
+		m := migrations[version]
+		if err := m.migrate(doc); err != nil {
+			return fmt.Errorf("error migrating board from version %d "+
+				"(%s): %v", version, m.description, err)
+		}
+		doc["version"] = version + 1
+	}
+
+	return nil
+}
+
+// docSeq returns the sequence stored in the given key of a document or nil if
+// there isn't one.
+func docSeq(doc yamlDoc, key string) []interface{} {
+	seq, _ := doc[key].([]interface{})
+	return seq
+}
+
+// docMaps calls f for every mapping in the sequence stored in the given key of
+// a document.
+func docMaps(doc yamlDoc, key string, f func(yamlDoc) error) error {
+	for i, item := range docSeq(doc, key) {
+		m, ok := item.(yamlDoc)
+		if !ok {
+			return fmt.Errorf("item %d of %s is not a mapping", i, key)
+		}
+		if err := f(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateV0AssignIDs assigns ids to every entity of the board, given that
+// version 0 boards did not have them.
+func migrateV0AssignIDs(doc yamlDoc) error {
+	fill := func(m yamlDoc) {
+		if id, _ := m["id"].(string); id == "" {
+			m["id"] = NewID()
+		}
+	}
+
+	fill(doc)
+	return docMaps(doc, "lists", func(l yamlDoc) error {
+		fill(l)
+		return docMaps(l, "tasks", func(t yamlDoc) error {
+			fill(t)
+			return docMaps(t, "times", func(tt yamlDoc) error {
+				fill(tt)
+				return nil
+			})
+		})
+	})
+}
+
+// migrateV1ParseTags fills the tags of every task with the #tag tokens of its
+// title, given that tags were only kept in titles before version 2.
+func migrateV1ParseTags(doc yamlDoc) error {
+	return docMaps(doc, "lists", func(l yamlDoc) error {
+		return docMaps(l, "tasks", func(t yamlDoc) error {
+			title, _ := t["title"].(string)
+			tags := ParseTags(title)
+			if len(tags) == 0 {
+				return nil
+			}
+			seq := make([]interface{}, len(tags))
+			for i, tag := range tags {
+				seq[i] = tag
+			}
+			t["tags"] = seq
+			return nil
+		})
+	})
+}
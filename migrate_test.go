@@ -0,0 +1,122 @@
+package nonota
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// checkFixtureBoard checks that a board loaded from one of the fixtures in
+// testdata has the expected contents, independently of its original version.
+func checkFixtureBoard(t *testing.T, b *Board) {
+	t.Helper()
+
+	if b.Version != SchemaVersion {
+		t.Fatalf("unexpected version: got %d, want %d", b.Version, SchemaVersion)
+	}
+	if b.ID == "" {
+		t.Fatalf("board without id")
+	}
+	if len(b.Lists) != 2 {
+		t.Fatalf("unexpected number of lists: %d", len(b.Lists))
+	}
+
+	ids := make(map[string]bool)
+	checkID := func(id, what string) {
+		if id == "" {
+			t.Fatalf("%s without id", what)
+		}
+		if ids[id] {
+			t.Fatalf("duplicated id %s in %s", id, what)
+		}
+		ids[id] = true
+	}
+
+	l := b.Lists[0]
+	checkID(l.ID, "list")
+	if l.Title != "Backlog" || len(l.Tasks) != 2 {
+		t.Fatalf("unexpected list %q with %d tasks", l.Title, len(l.Tasks))
+	}
+	checkID(b.Lists[1].ID, "list")
+
+	task := l.Tasks[0]
+	checkID(task.ID, "task")
+	checkID(l.Tasks[1].ID, "task")
+	if task.Title != "Write the docs #docs" {
+		t.Fatalf("unexpected task title %q", task.Title)
+	}
+	if !reflect.DeepEqual(task.Tags, []string{"docs"}) {
+		t.Fatalf("unexpected task tags %v", task.Tags)
+	}
+	if len(l.Tasks[1].Tags) != 0 {
+		t.Fatalf("unexpected task tags %v", l.Tasks[1].Tags)
+	}
+	if task.Description != "Describe how to install.\n\nAnd how to run." {
+		t.Fatalf("unexpected task description %q", task.Description)
+	}
+	if len(task.Times) != 2 {
+		t.Fatalf("unexpected number of times: %d", len(task.Times))
+	}
+
+	tt := task.Times[0]
+	checkID(tt.ID, "task time")
+	checkID(task.Times[1].ID, "task time")
+	brt := time.FixedZone("", -3*60*60)
+	wantStart := time.Date(2019, 3, 1, 10, 0, 0, 0, brt)
+	wantEnd := time.Date(2019, 3, 1, 11, 30, 0, 0, brt)
+	if !tt.Start.Equal(wantStart) || !tt.End.Equal(wantEnd) {
+		t.Fatalf("unexpected times %s - %s", tt.Start, tt.End)
+	}
+	if tt.Duration != 85*time.Minute || tt.Note != "first draft" {
+		t.Fatalf("unexpected duration %s and note %q", tt.Duration, tt.Note)
+	}
+}
+
+func TestMigrateFixtures(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "board-v*.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) != SchemaVersion+1 {
+		t.Fatalf("expected one fixture per schema version, found %d",
+			len(fixtures))
+	}
+
+	dir, err := ioutil.TempDir("", "nonota")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, fixture := range fixtures {
+		b, err := BoardFromFile(fixture)
+		if err != nil {
+			t.Fatalf("%s: %v", fixture, err)
+		}
+		checkFixtureBoard(t, b)
+
+		// Saving and loading the migrated board must not change it.
+		fname := filepath.Join(dir, filepath.Base(fixture))
+		if err := BoardToFile(fname, b); err != nil {
+			t.Fatalf("%s: %v", fixture, err)
+		}
+		reloaded, err := BoardFromFile(fname)
+		if err != nil {
+			t.Fatalf("%s: %v", fixture, err)
+		}
+		checkFixtureBoard(t, reloaded)
+		if reloaded.Lists[0].Tasks[0].ID != b.Lists[0].Tasks[0].ID {
+			t.Fatalf("%s: ids changed after reloading", fixture)
+		}
+	}
+}
+
+func TestMigrateRefusesFutureVersions(t *testing.T) {
+	_, err := BoardFromFile(filepath.Join("testdata", "board-future.yml"))
+	if err == nil {
+		t.Fatalf("expected error loading board from a future version")
+	}
+}
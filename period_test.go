@@ -0,0 +1,219 @@
+package nonota
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDurationWithin(t *testing.T) {
+	const format = "2006-01-02 15:04:05"
+
+	type testCase struct {
+		name     string
+		start    string
+		end      string
+		duration time.Duration
+		from     string
+		to       string
+		expected time.Duration
+	}
+	testCases := []testCase{
+		{"fully inside", "2019-03-20 10:00:00", "2019-03-20 12:00:00", 2 * time.Hour,
+			"2019-03-20 00:00:00", "2019-03-20 23:59:59", 2 * time.Hour},
+		{"starts at period start", "2019-03-01 00:00:00", "2019-03-01 01:00:00", time.Hour,
+			"2019-03-01 00:00:00", "2019-03-31 23:59:59", time.Hour},
+		{"ends at period end", "2019-03-31 23:00:00", "2019-03-31 23:59:59", 30 * time.Minute,
+			"2019-03-01 00:00:00", "2019-03-31 23:59:59", 30 * time.Minute},
+		{"ends at next period start", "2019-03-31 23:00:00", "2019-04-01 00:00:00", time.Hour,
+			"2019-03-01 00:00:00", "2019-03-31 23:59:59", time.Hour},
+		{"starts at next period start", "2019-04-01 00:00:00", "2019-04-01 01:00:00", time.Hour,
+			"2019-03-01 00:00:00", "2019-03-31 23:59:59", 0},
+		{"ends at period start", "2019-02-28 23:00:00", "2019-03-01 00:00:00", time.Hour,
+			"2019-03-01 00:00:00", "2019-03-31 23:59:59", 0},
+		{"before period", "2019-02-10 10:00:00", "2019-02-10 12:00:00", 2 * time.Hour,
+			"2019-03-01 00:00:00", "2019-03-31 23:59:59", 0},
+		{"after period", "2019-04-10 10:00:00", "2019-04-10 12:00:00", 2 * time.Hour,
+			"2019-03-01 00:00:00", "2019-03-31 23:59:59", 0},
+
+		// Entries crossing midnight.
+		{"across midnight, first day", "2019-03-20 23:00:00", "2019-03-21 01:00:00", 2 * time.Hour,
+			"2019-03-20 00:00:00", "2019-03-20 23:59:59", time.Hour},
+		{"across midnight, second day", "2019-03-20 23:00:00", "2019-03-21 01:00:00", 2 * time.Hour,
+			"2019-03-21 00:00:00", "2019-03-21 23:59:59", time.Hour},
+		{"across midnight with pauses", "2019-03-20 22:00:00", "2019-03-21 02:00:00", 2 * time.Hour,
+			"2019-03-21 00:00:00", "2019-03-21 23:59:59", time.Hour},
+		{"uneven split", "2019-03-20 23:30:00", "2019-03-21 01:00:00", 90 * time.Minute,
+			"2019-03-20 00:00:00", "2019-03-20 23:59:59", 30 * time.Minute},
+
+		// Entries crossing a month end.
+		{"across month end, first month", "2019-02-28 20:00:00", "2019-03-01 04:00:00", 4 * time.Hour,
+			"2019-02-01 00:00:00", "2019-02-28 23:59:59", 2 * time.Hour},
+		{"across month end, second month", "2019-02-28 20:00:00", "2019-03-01 04:00:00", 4 * time.Hour,
+			"2019-03-01 00:00:00", "2019-03-31 23:59:59", 2 * time.Hour},
+
+		// Entries containing the whole period.
+		{"containing the period", "2019-03-19 00:00:00", "2019-03-22 00:00:00", 6 * time.Hour,
+			"2019-03-20 00:00:00", "2019-03-20 23:59:59", 2 * time.Hour},
+
+		// Entries without a timeframe are counted where they start.
+		{"instant at period start", "2019-03-01 00:00:00", "2019-03-01 00:00:00", time.Hour,
+			"2019-03-01 00:00:00", "2019-03-31 23:59:59", time.Hour},
+		{"instant at period end", "2019-03-31 23:59:59", "2019-03-31 23:59:59", time.Hour,
+			"2019-03-01 00:00:00", "2019-03-31 23:59:59", time.Hour},
+		{"instant at next period start", "2019-04-01 00:00:00", "2019-04-01 00:00:00", time.Hour,
+			"2019-03-01 00:00:00", "2019-03-31 23:59:59", 0},
+		{"end before start", "2019-03-20 10:00:00", "2019-03-20 09:00:00", time.Hour,
+			"2019-03-20 00:00:00", "2019-03-20 23:59:59", time.Hour},
+	}
+
+	parse := func(s string) time.Time {
+		res, err := time.ParseInLocation(format, s, time.Local)
+		if err != nil {
+			t.Fatalf("unable to decode test time %s: %v", s, err)
+		}
+		return res
+	}
+
+	for _, tc := range testCases {
+		tt := &TaskTime{
+			Start:    parse(tc.start),
+			End:      parse(tc.end),
+			Duration: tc.duration,
+		}
+		actual := tt.DurationWithin(parse(tc.from), parse(tc.to))
+		if actual != tc.expected {
+			t.Fatalf("%s: expected %s found %s", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+// TestDurationWithinSplitsExactly ensures the parts of an entry that crosses
+// several periods add up to its whole duration.
+func TestDurationWithinSplitsExactly(t *testing.T) {
+	start := time.Date(2019, 3, 19, 17, 13, 7, 0, time.Local)
+	tt := &TaskTime{
+		Start:    start,
+		End:      start.Add(53*time.Hour + 17*time.Second),
+		Duration: 31*time.Hour + 7*time.Minute + 3*time.Second + 1,
+	}
+
+	var total time.Duration
+	for day := StartOfDay(tt.Start); day.Before(tt.End); day = StartOfDay(day.AddDate(0, 0, 1)) {
+		total += tt.DurationWithin(day, EndOfDay(day))
+	}
+	if total != tt.Duration {
+		t.Fatalf("expected %s found %s", tt.Duration, total)
+	}
+}
+
+// TestTotalTimePeriods ensures entries crossing period boundaries are
+// accounted for in the day, week and billing totals.
+func TestTotalTimePeriods(t *testing.T) {
+	// Sunday, March 31st to Monday, April 1st.
+	start := time.Date(2019, 3, 31, 22, 0, 0, 0, time.Local)
+	task := &Task{}
+	task.AddTaskTime(&TaskTime{
+		Start:    start,
+		End:      start.Add(4 * time.Hour),
+		Duration: 4 * time.Hour,
+	})
+
+	// Starts exactly at the start of the month.
+	april := time.Date(2019, 4, 1, 0, 0, 0, 0, time.Local)
+	task.AddTaskTime(&TaskTime{
+		Start:    april,
+		End:      april.Add(time.Hour),
+		Duration: time.Hour,
+	})
+
+	type testCase struct {
+		name     string
+		from     time.Time
+		to       time.Time
+		expected time.Duration
+	}
+	testCases := []testCase{
+		{"march 31st", StartOfDay(start), EndOfDay(start), 2 * time.Hour},
+		{"april 1st", StartOfDay(april), EndOfDay(april), 3 * time.Hour},
+		{"week of march 31st", StartOfWeek(start), EndOfWeek(start), 5 * time.Hour},
+		{"march", StartOfBilling(start), EndOfBilling(start), 2 * time.Hour},
+		{"april", StartOfBilling(april), EndOfBilling(april), 3 * time.Hour},
+		{"march and april", StartOfBilling(start), EndOfBilling(april), 5 * time.Hour},
+	}
+
+	for _, tc := range testCases {
+		actual := task.TotalTime(tc.from, tc.to)
+		if actual != tc.expected {
+			t.Fatalf("%s: expected %s found %s", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+// TestPeriodsInBoardTimeZone ensures the totals of a board only depend on its
+// time zone setting and not on the time zone of the machine.
+func TestPeriodsInBoardTimeZone(t *testing.T) {
+	brt := time.FixedZone("BRT", -3*60*60)
+	start := time.Date(2019, 3, 31, 23, 30, 0, 0, brt)
+	task := &Task{}
+	task.AddTaskTime(&TaskTime{
+		Start:    start,
+		End:      start.Add(time.Hour),
+		Duration: time.Hour,
+	})
+
+	type testCase struct {
+		tz       string
+		local    string
+		day      time.Duration
+		billing  time.Duration
+		startDay int
+	}
+	testCases := []testCase{
+		{"America/Sao_Paulo", "Asia/Tokyo", 30 * time.Minute, 30 * time.Minute, 31},
+		{"America/Sao_Paulo", "UTC", 30 * time.Minute, 30 * time.Minute, 31},
+		{"Asia/Tokyo", "America/Sao_Paulo", time.Hour, time.Hour, 1},
+		{"UTC", "America/Sao_Paulo", time.Hour, time.Hour, 1},
+	}
+
+	oldLocal := time.Local
+	defer func() { time.Local = oldLocal }()
+
+	for _, tc := range testCases {
+		local, err := time.LoadLocation(tc.local)
+		if err != nil {
+			t.Skipf("time zone database not available: %v", err)
+		}
+		time.Local = local
+
+		settings := &Settings{TimeZone: tc.tz}
+		if err := settings.Validate(); err != nil {
+			t.Fatalf("%s: unexpected error %v", tc.tz, err)
+		}
+		ref := start.In(settings.Location())
+		if ref.Day() != tc.startDay {
+			t.Fatalf("%s: expected day %d found %d", tc.tz, tc.startDay,
+				ref.Day())
+		}
+
+		day := task.TotalTime(StartOfDay(ref), EndOfDay(ref))
+		if day != tc.day {
+			t.Fatalf("%s: expected day total %s found %s", tc.tz, tc.day, day)
+		}
+		billing := settings.Billing
+		total := task.TotalTime(billing.Start(ref), billing.End(ref))
+		if total != tc.billing {
+			t.Fatalf("%s: expected billing total %s found %s", tc.tz,
+				tc.billing, total)
+		}
+
+		// The entry keeps its recorded offset.
+		if _, offset := task.Times[0].Start.Zone(); offset != -3*60*60 {
+			t.Fatalf("%s: entry offset changed to %d", tc.tz, offset)
+		}
+	}
+
+	settings := &Settings{TimeZone: "Nowhere/Invalid"}
+	if err := settings.Validate(); err == nil {
+		t.Fatalf("expected an error for an invalid time zone")
+	}
+}
@@ -0,0 +1,175 @@
+package nonota
+
+import (
+	"sync"
+	"time"
+)
+
+// Default pomodoro settings.
+const (
+	DefaultPomodoroWork       = 25 * time.Minute
+	DefaultPomodoroShortBreak = 5 * time.Minute
+	DefaultPomodoroLongBreak  = 15 * time.Minute
+	DefaultPomodoroLongEvery  = 4
+)
+
+// PomodoroSettings configures the lengths of the phases of a pomodoro.
+// Zero values mean the defaults.
+type PomodoroSettings struct {
+	Work       time.Duration `yaml:",omitempty"`
+	ShortBreak time.Duration `yaml:",omitempty"`
+	LongBreak  time.Duration `yaml:",omitempty"`
+
+	// LongBreakEvery is the number of pomodoros after which the break is
+	// a long one.
+	LongBreakEvery int `yaml:",omitempty"`
+}
+
+// withDefaults returns the settings with the unset values replaced by the
+// defaults.
+func (s PomodoroSettings) withDefaults() PomodoroSettings {
+	if s.Work <= 0 {
+		s.Work = DefaultPomodoroWork
+	}
+	if s.ShortBreak <= 0 {
+		s.ShortBreak = DefaultPomodoroShortBreak
+	}
+	if s.LongBreak <= 0 {
+		s.LongBreak = DefaultPomodoroLongBreak
+	}
+	if s.LongBreakEvery <= 0 {
+		s.LongBreakEvery = DefaultPomodoroLongEvery
+	}
+	return s
+}
+
+// PomodoroPhase is the current phase of a pomodoro.
+type PomodoroPhase int
+
+const (
+	PomodoroWork PomodoroPhase = iota
+	PomodoroShortBreak
+	PomodoroLongBreak
+)
+
+func (p PomodoroPhase) String() string {
+	switch p {
+	case PomodoroShortBreak:
+		return "short break"
+	case PomodoroLongBreak:
+		return "long break"
+	default:
+		return "work"
+	}
+}
+
+// Pomodoro runs focus sessions on top of a work: the work is resumed during
+// work phases and automatically paused during breaks. Every completed work
+// phase is recorded as a pomodoro of the work's time entry.
+//
+// Pomodoro is safe for concurrent use.
+type Pomodoro struct {
+	mu         sync.Mutex
+	clock      Clock
+	settings   PomodoroSettings
+	work       *Work
+	phase      PomodoroPhase
+	phaseStart time.Time
+	completed  int
+}
+
+// StartPomodoro starts a pomodoro on the given work, beginning with a work
+// phase. A nil clock means the system clock.
+func StartPomodoro(work *Work, settings PomodoroSettings, clock Clock) *Pomodoro {
+	clock = clockOrSystem(clock)
+	work.ResumeWork()
+	return &Pomodoro{
+		clock:      clock,
+		settings:   settings.withDefaults(),
+		work:       work,
+		phase:      PomodoroWork,
+		phaseStart: clock.Now(),
+	}
+}
+
+// length returns the length of the given phase. It must be called with the
+// mutex held.
+func (p *Pomodoro) length(phase PomodoroPhase) time.Duration {
+	switch phase {
+	case PomodoroShortBreak:
+		return p.settings.ShortBreak
+	case PomodoroLongBreak:
+		return p.settings.LongBreak
+	default:
+		return p.settings.Work
+	}
+}
+
+// Update moves the pomodoro to the next phase if the current one ended by now,
+// pausing or resuming the work as needed. It returns the number of transitions
+// that happened (at most one), so that they may be signaled to the user. The
+// work is only paused or resumed when this is called, so it should be called
+// frequently (such as every second).
+//
+// If the next phase would also have ended by now (for example, after the
+// computer was suspended), it starts now instead: the phases that would have
+// passed in the meantime weren't actually worked or rested, so they are
+// neither counted as pomodoros nor billed as work.
+func (p *Pomodoro) Update() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.clock.Now()
+	end := p.phaseStart.Add(p.length(p.phase))
+	if now.Before(end) {
+		return 0
+	}
+
+	if p.phase == PomodoroWork {
+		p.completed++
+		p.work.CompletePomodoro()
+		p.work.PauseWork()
+		p.phase = PomodoroShortBreak
+		if p.completed%p.settings.LongBreakEvery == 0 {
+			p.phase = PomodoroLongBreak
+		}
+	} else {
+		p.work.ResumeWork()
+		p.phase = PomodoroWork
+	}
+	p.phaseStart = end
+	if !now.Before(end.Add(p.length(p.phase))) {
+		p.phaseStart = now
+	}
+	return 1
+}
+
+// Work returns the work the pomodoro runs on.
+func (p *Pomodoro) Work() *Work {
+	return p.work
+}
+
+// Phase returns the current phase of the pomodoro.
+func (p *Pomodoro) Phase() PomodoroPhase {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.phase
+}
+
+// Remaining returns how long until the end of the current phase.
+func (p *Pomodoro) Remaining() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	remaining := p.phaseStart.Add(p.length(p.phase)).Sub(p.clock.Now())
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// Completed returns the number of pomodoros completed in this session.
+func (p *Pomodoro) Completed() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.completed
+}
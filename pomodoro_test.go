@@ -0,0 +1,89 @@
+package nonota
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPomodoro(t *testing.T) {
+	start := time.Date(2019, 3, 20, 10, 0, 0, 0, time.Local)
+	clock := NewManualClock(start)
+	task := &Task{ID: NewID()}
+	work := StartWorkWithClock(task, clock)
+	work.PauseWork()
+
+	settings := PomodoroSettings{
+		Work:           20 * time.Minute,
+		LongBreakEvery: 2,
+	}
+	p := StartPomodoro(work, settings, clock)
+	if work.IsPaused() {
+		t.Fatalf("work should be resumed by the pomodoro")
+	}
+
+	type step struct {
+		advance     time.Duration
+		transitions int
+		phase       PomodoroPhase
+		remaining   time.Duration
+		completed   int
+		paused      bool
+	}
+	steps := []step{
+		{10 * time.Minute, 0, PomodoroWork, 10 * time.Minute, 0, false},
+		{10 * time.Minute, 1, PomodoroShortBreak, 5 * time.Minute, 1, true},
+		{5 * time.Minute, 1, PomodoroWork, 20 * time.Minute, 1, false},
+		{21 * time.Minute, 1, PomodoroLongBreak, 14 * time.Minute, 2, true},
+		{14 * time.Minute, 1, PomodoroWork, 20 * time.Minute, 2, false},
+
+		// Only one transition after a long gap (for example, after a
+		// suspend), with the next phase starting at the end of the gap.
+		{30 * time.Minute, 1, PomodoroShortBreak, 5 * time.Minute, 3, true},
+		{5 * time.Minute, 1, PomodoroWork, 20 * time.Minute, 3, false},
+
+		// Long gaps during breaks don't complete pomodoros.
+		{20 * time.Minute, 1, PomodoroLongBreak, 15 * time.Minute, 4, true},
+		{time.Hour, 1, PomodoroWork, 20 * time.Minute, 4, false},
+	}
+
+	for i, s := range steps {
+		clock.Advance(s.advance)
+		if n := p.Update(); n != s.transitions {
+			t.Fatalf("step %d: expected %d transitions found %d", i,
+				s.transitions, n)
+		}
+		if p.Phase() != s.phase {
+			t.Fatalf("step %d: expected phase %s found %s", i, s.phase,
+				p.Phase())
+		}
+		if p.Remaining() != s.remaining {
+			t.Fatalf("step %d: expected remaining %s found %s", i,
+				s.remaining, p.Remaining())
+		}
+		if p.Completed() != s.completed {
+			t.Fatalf("step %d: expected %d completed found %d", i,
+				s.completed, p.Completed())
+		}
+		if work.IsPaused() != s.paused {
+			t.Fatalf("step %d: expected paused %v", i, s.paused)
+		}
+	}
+
+	// The work is paused and resumed when the pomodoro is updated, so only
+	// the time between updates of the breaks is not counted: 1m (initial)
+	// + 20m + 21m + 30m + 20m.
+	if d := work.CurrentDuration(); d != 92*time.Minute {
+		t.Fatalf("unexpected work duration %s", d)
+	}
+
+	// Pomodoros are recorded in the time entry.
+	if err := work.StopWork(); err != nil {
+		t.Fatal(err)
+	}
+	if task.Times[0].Pomodoros != 4 {
+		t.Fatalf("expected 4 pomodoros found %d", task.Times[0].Pomodoros)
+	}
+	if n := task.PomodorosWithin(StartOfDay(start), EndOfDay(start)); n != 4 {
+		t.Fatalf("expected 4 pomodoros in the day found %d", n)
+	}
+}
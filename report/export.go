@@ -0,0 +1,110 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/matheusd/nonota"
+)
+
+// DefaultBoardFile is the board file exported when none is given.
+const DefaultBoardFile = "nonota-board.yml"
+
+// ExportOptions are the options shared by the exporters (such as nonota-csv)
+// to select the board and the billing period to export.
+type ExportOptions struct {
+	// Filename is the board file. Defaults to DefaultBoardFile.
+	Filename string
+
+	// Date is a date within the billing period to export, in the
+	// YYYY-MM-DD or YYYY-MM format. If empty, the previous billing period
+	// is exported, unless Current is set.
+	Date    string
+	Current bool
+
+	// TZ is the name of the time zone used to compute the billing period,
+	// overriding the one of the board.
+	TZ string
+
+	// Now is the time the previous and current billing periods are
+	// relative to. Defaults to the current time.
+	Now time.Time
+}
+
+// Export is a board loaded by an exporter along with the period to export.
+type Export struct {
+	Board    *nonota.Board
+	Location *time.Location
+
+	// From and To are the first and last instants of the exported
+	// period.
+	From time.Time
+	To   time.Time
+}
+
+// LoadExport loads the board selected by the options and computes the billing
+// period to export.
+func LoadExport(opts ExportOptions) (*Export, error) {
+	filename := opts.Filename
+	if filename == "" {
+		filename = DefaultBoardFile
+	}
+	storage, err := nonota.OpenStorage(filename, nonota.StorageOptions{})
+	if err != nil {
+		return nil, err
+	}
+	board, err := storage.Load()
+	storage.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	loc := board.Settings.Location()
+	if opts.TZ != "" {
+		loc, err = time.LoadLocation(opts.TZ)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	billing := board.Settings.Billing
+	ref := now.In(loc)
+	if opts.Date != "" {
+		date, err := time.ParseInLocation(DateFormat, opts.Date, loc)
+		if err != nil {
+			date, err = time.ParseInLocation("2006-01", opts.Date, loc)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q", opts.Date)
+		}
+		ref = date
+	} else if !opts.Current {
+		ref = billing.Previous(ref)
+	}
+
+	return &Export{
+		Board:    board,
+		Location: loc,
+		From:     billing.Start(ref),
+		To:       billing.End(ref),
+	}, nil
+}
+
+// Check looks for issues in the entries of the exported period (see
+// nonota.Board.CheckPeriod), writing them to w. Inconsistent times (such as
+// overlapping ones) would end up being billed twice, so exporters should
+// refuse to export them unless told otherwise.
+func (e *Export) Check(w io.Writer) error {
+	err := e.Board.CheckPeriod(e.From, e.To)
+	if verr, ok := err.(*nonota.ValidationError); ok {
+		for _, issue := range verr.Issues {
+			fmt.Fprintln(w, issue)
+		}
+	}
+	return err
+}
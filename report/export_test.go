@@ -0,0 +1,130 @@
+package report
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matheusd/nonota"
+)
+
+func TestLoadExport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nonota")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "board.yml")
+
+	b := testBoard()
+	b.Settings.TimeZone = "America/Sao_Paulo"
+	if err := nonota.BoardToFile(filename, b); err != nil {
+		t.Fatal(err)
+	}
+	brt, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2019, 4, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		opts ExportOptions
+		from time.Time
+		loc  *time.Location
+	}{{
+		name: "previous",
+		opts: ExportOptions{},
+		from: time.Date(2019, 3, 1, 0, 0, 0, 0, brt),
+		loc:  brt,
+	}, {
+		name: "current",
+		opts: ExportOptions{Current: true},
+		from: time.Date(2019, 4, 1, 0, 0, 0, 0, brt),
+		loc:  brt,
+	}, {
+		name: "day",
+		opts: ExportOptions{Date: "2019-02-15"},
+		from: time.Date(2019, 2, 1, 0, 0, 0, 0, brt),
+		loc:  brt,
+	}, {
+		name: "month",
+		opts: ExportOptions{Date: "2019-01"},
+		from: time.Date(2019, 1, 1, 0, 0, 0, 0, brt),
+		loc:  brt,
+	}, {
+		name: "tz",
+		opts: ExportOptions{TZ: "UTC"},
+		from: time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC),
+		loc:  time.UTC,
+	}}
+	for _, tc := range tests {
+		tc.opts.Filename = filename
+		tc.opts.Now = now
+		exp, err := LoadExport(tc.opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if exp.Location.String() != tc.loc.String() {
+			t.Fatalf("%s: unexpected location %s", tc.name, exp.Location)
+		}
+		if !exp.From.Equal(tc.from) {
+			t.Fatalf("%s: unexpected start %s", tc.name, exp.From)
+		}
+		to := tc.from.AddDate(0, 1, 0).Add(-time.Second)
+		if !exp.To.Equal(to) {
+			t.Fatalf("%s: unexpected end %s", tc.name, exp.To)
+		}
+		if len(exp.Board.Lists) != len(b.Lists) {
+			t.Fatalf("%s: unexpected board %#v", tc.name, exp.Board)
+		}
+	}
+
+	_, err = LoadExport(ExportOptions{Filename: filename, Date: "2019"})
+	if err == nil {
+		t.Fatalf("expected error for invalid date")
+	}
+	_, err = LoadExport(ExportOptions{Filename: filename, TZ: "Nowhere/Else"})
+	if err == nil {
+		t.Fatalf("expected error for invalid time zone")
+	}
+}
+
+func TestExportCheck(t *testing.T) {
+	b := testBoard()
+	tt := b.Lists[0].Tasks[0].Times[0]
+	overlap := &nonota.TaskTime{
+		ID:       nonota.NewID(),
+		Start:    tt.Start,
+		End:      tt.End,
+		Duration: tt.End.Sub(tt.Start),
+	}
+	b.Lists[0].Tasks[0].AddTaskTime(overlap)
+
+	exp := &Export{
+		Board:    b,
+		Location: time.UTC,
+		From:     time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:       time.Date(2019, 3, 31, 23, 59, 59, 0, time.UTC),
+	}
+	var buf bytes.Buffer
+	if err := exp.Check(&buf); err == nil {
+		t.Fatalf("expected error checking overlapping entries")
+	}
+	if !strings.Contains(buf.String(), string(nonota.IssueOverlap)) {
+		t.Fatalf("overlap not reported: %q", buf.String())
+	}
+
+	// Issues outside of the exported period don't prevent the export.
+	exp.From = time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC)
+	exp.To = time.Date(2019, 4, 30, 23, 59, 59, 0, time.UTC)
+	buf.Reset()
+	if err := exp.Check(&buf); err != nil || buf.Len() != 0 {
+		t.Fatalf("unexpected issues outside of the period: %v %q", err,
+			buf.String())
+	}
+}
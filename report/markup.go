@@ -0,0 +1,141 @@
+package report
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// timesheetTitle returns the title of a timesheet of the report.
+func timesheetTitle(title string, r *Report) string {
+	if title == "" {
+		title = "Timesheet"
+	}
+	return fmt.Sprintf("%s: %s to %s", title, r.From.Format(DateFormat),
+		r.To.Format(DateFormat))
+}
+
+// details returns the lines with the details of a node: the description of
+// its task (if it's a task node) and the notes of its entries.
+func details(n *Node) (descr []string, notes []string) {
+	if n.Task != nil && strings.TrimSpace(n.Task.Description) != "" {
+		for _, line := range strings.Split(strings.TrimSpace(n.Task.Description), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				descr = append(descr, line)
+			}
+		}
+	}
+	return descr, n.Notes()
+}
+
+// MarkdownRenderer renders the report as a Markdown timesheet: a table with a
+// subtotal row for every group and a row with the description and notes of
+// every innermost group.
+type MarkdownRenderer struct {
+	Title string
+}
+
+// escapeMarkdown escapes text to be placed in a cell of a Markdown table.
+func escapeMarkdown(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "|", `\|`)
+	s = strings.ReplaceAll(s, "*", `\*`)
+	s = strings.ReplaceAll(s, "_", `\_`)
+	s = strings.ReplaceAll(s, "<", "&lt;")
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+// Render implements Renderer.
+func (m MarkdownRenderer) Render(w io.Writer, r *Report) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", escapeMarkdown(timesheetTitle(m.Title, r)))
+	b.WriteString("| Item | Details | Hours |\n")
+	b.WriteString("| --- | --- | ---: |\n")
+
+	r.Root.Walk(func(path []*Node) {
+		n := path[len(path)-1]
+		indent := strings.Repeat("&nbsp;&nbsp;", len(path)-1)
+		title := escapeMarkdown(n.Title)
+		if len(n.Children) > 0 {
+			fmt.Fprintf(&b, "| %s**%s** | | **%.2f** |\n", indent, title,
+				n.Duration.Hours())
+			return
+		}
+
+		var lines []string
+		descr, notes := details(n)
+		for _, l := range descr {
+			lines = append(lines, escapeMarkdown(l))
+		}
+		for _, note := range notes {
+			lines = append(lines, "• "+escapeMarkdown(note))
+		}
+		fmt.Fprintf(&b, "| %s%s | %s | %.2f |\n", indent, title,
+			strings.Join(lines, "<br>"), n.Duration.Hours())
+	})
+	fmt.Fprintf(&b, "| **Total** | | **%.2f** |\n", r.Root.Duration.Hours())
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// HTMLRenderer renders the report as a self-contained HTML timesheet, with the
+// same layout as MarkdownRenderer.
+type HTMLRenderer struct {
+	Title string
+}
+
+const htmlStyle = `body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ddd; padding: 0.4em 0.6em; text-align: left; vertical-align: top; }
+th.hours, td.hours { text-align: right; white-space: nowrap; }
+tr.group td { font-weight: bold; background: #f4f4f4; }
+tr.total td { font-weight: bold; border-top: 2px solid #222; }
+.descr { white-space: pre-line; }
+ul.notes { margin: 0.2em 0 0 0; padding-left: 1.2em; color: #555; }`
+
+// Render implements Renderer.
+func (h HTMLRenderer) Render(w io.Writer, r *Report) error {
+	var b strings.Builder
+	title := html.EscapeString(timesheetTitle(h.Title, r))
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n"+
+		"<title>%s</title>\n<style>\n%s\n</style>\n</head>\n<body>\n"+
+		"<h1>%s</h1>\n<table>\n"+
+		"<tr><th>Item</th><th>Details</th><th class=\"hours\">Hours</th></tr>\n",
+		title, htmlStyle, title)
+
+	r.Root.Walk(func(path []*Node) {
+		n := path[len(path)-1]
+		pad := fmt.Sprintf(" style=\"padding-left: %.1fem\"", 0.6+1.5*float64(len(path)-1))
+		title := html.EscapeString(n.Title)
+		if len(n.Children) > 0 {
+			fmt.Fprintf(&b, "<tr class=\"group\"><td%s>%s</td><td></td>"+
+				"<td class=\"hours\">%.2f</td></tr>\n", pad, title,
+				n.Duration.Hours())
+			return
+		}
+
+		descr, notes := details(n)
+		fmt.Fprintf(&b, "<tr><td%s>%s</td><td>", pad, title)
+		if len(descr) > 0 {
+			fmt.Fprintf(&b, "<div class=\"descr\">%s</div>",
+				html.EscapeString(strings.Join(descr, "\n")))
+		}
+		if len(notes) > 0 {
+			b.WriteString("<ul class=\"notes\">")
+			for _, note := range notes {
+				fmt.Fprintf(&b, "<li>%s</li>", html.EscapeString(note))
+			}
+			b.WriteString("</ul>")
+		}
+		fmt.Fprintf(&b, "</td><td class=\"hours\">%.2f</td></tr>\n",
+			n.Duration.Hours())
+	})
+	fmt.Fprintf(&b, "<tr class=\"total\"><td>Total</td><td></td>"+
+		"<td class=\"hours\">%.2f</td></tr>\n</table>\n</body>\n</html>\n",
+		r.Root.Duration.Hours())
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,76 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Renderer writes a report in some output format.
+type Renderer interface {
+	Render(w io.Writer, r *Report) error
+}
+
+// Renderers are the available renderers, by name.
+var Renderers = map[string]Renderer{
+	"text":     TextRenderer{},
+	"csv":      CSVRenderer{Header: true},
+	"markdown": MarkdownRenderer{},
+	"html":     HTMLRenderer{},
+}
+
+// TextRenderer renders the report as an indented tree of totals, meant to be
+// read on a terminal.
+type TextRenderer struct{}
+
+// Render implements Renderer.
+func (TextRenderer) Render(w io.Writer, r *Report) error {
+	var err error
+	line := func(depth int, n *Node) {
+		if err != nil {
+			return
+		}
+		_, err = fmt.Fprintf(w, "%-50s %10s %8.2f\n",
+			strings.Repeat("  ", depth)+n.Title, n.Duration, n.Duration.Hours())
+	}
+
+	r.Root.Walk(func(path []*Node) {
+		line(len(path)-1, path[len(path)-1])
+	})
+	line(0, r.Root)
+	return err
+}
+
+// CSVRenderer renders the report as a csv with one row per innermost group.
+// There is a column with the title of the group of each level, followed by
+// the total hours of the row.
+type CSVRenderer struct {
+	Header bool
+}
+
+// Render implements Renderer.
+func (c CSVRenderer) Render(w io.Writer, r *Report) error {
+	cw := csv.NewWriter(w)
+	if c.Header {
+		header := make([]string, 0, len(r.Groups)+1)
+		for _, g := range r.Groups {
+			header = append(header, string(g))
+		}
+		cw.Write(append(header, "hours"))
+	}
+
+	r.Root.Walk(func(path []*Node) {
+		n := path[len(path)-1]
+		if len(n.Children) > 0 {
+			return
+		}
+		record := make([]string, 0, len(path)+1)
+		for _, p := range path {
+			record = append(record, p.Title)
+		}
+		cw.Write(append(record, fmt.Sprintf("%.2f", n.Duration.Hours())))
+	})
+	cw.Flush()
+	return cw.Error()
+}
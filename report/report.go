@@ -0,0 +1,375 @@
+// Package report aggregates the time recorded on a board into a tree of
+// totals, grouped by list, task, tag, day or week, which can then be rendered
+// in several formats.
+//
+// Every tool that displays totals should build them through this package, so
+// that entries crossing period boundaries, tasks with multiple tags and time
+// zones are accounted for the same way everywhere.
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/matheusd/nonota"
+)
+
+// GroupBy identifies how the time is grouped at one level of a report.
+type GroupBy string
+
+const (
+	GroupList GroupBy = "list"
+	GroupTask GroupBy = "task"
+
+	// GroupTag groups the time by the tags of the tasks. Tasks with
+	// multiple tags count towards each of them, so the sum of the tag
+	// groups may be larger than the total of their parent.
+	GroupTag GroupBy = "tag"
+
+	GroupDay  GroupBy = "day"
+	GroupWeek GroupBy = "week"
+)
+
+// DateFormat is the format of the keys of day and week groups.
+const DateFormat = "2006-01-02"
+
+// ParseGroups parses a grouping spec such as "list,task,day" (levels may
+// also be separated by ">" or "/").
+func ParseGroups(spec string) ([]GroupBy, error) {
+	fields := strings.FieldsFunc(spec, func(r rune) bool {
+		return r == ',' || r == '>' || r == '/' || r == ' '
+	})
+	groups := make([]GroupBy, 0, len(fields))
+	for _, f := range fields {
+		g := GroupBy(strings.ToLower(f))
+		switch g {
+		case GroupList, GroupTask, GroupTag, GroupDay, GroupWeek:
+		default:
+			return nil, fmt.Errorf("unknown grouping %q", f)
+		}
+		groups = append(groups, g)
+	}
+	return groups, nil
+}
+
+// Options select the time included in a report and how it's grouped.
+type Options struct {
+	// From and To are the first and last instants of the reported period,
+	// following the convention of the nonota period functions (such as
+	// nonota.StartOfDay and nonota.EndOfDay). Days and weeks are computed
+	// in the location of From.
+	From, To time.Time
+
+	// Groups are the levels of the report, from the outermost to the
+	// innermost.
+	Groups []GroupBy
+
+	// Tag, if set, restricts the report to tasks with the given tag.
+	Tag string
+}
+
+// Node is a group of the report, with the total time recorded in it.
+type Node struct {
+	// Group is how the node was grouped. It's empty for the root node.
+	Group GroupBy
+
+	// Key identifies the node among its siblings: the id of the list or
+	// task, the tag or the date (see DayKey) of the day or week.
+	Key string
+
+	// Title is the name of the node as displayed to users.
+	Title string
+
+	// List and Task are set on list and task groups, Tag on tag groups and
+	// Start and End on day and week groups.
+	List       *nonota.List
+	Task       *nonota.Task
+	Tag        string
+	Start, End time.Time
+
+	Duration  time.Duration
+	Pomodoros int
+
+	// Entries are the time entries with time in the group, in the order
+	// they appear on the board.
+	Entries []*nonota.TaskTime
+
+	Children []*Node
+
+	children map[string]*Node
+}
+
+// Child returns the child of the node with the given key or nil. It may be
+// called on a nil node, so that descendants may be looked up without checking
+// every level.
+func (n *Node) Child(key string) *Node {
+	if n == nil {
+		return nil
+	}
+	return n.children[key]
+}
+
+// TotalTime returns the time recorded in the group or zero if the node is
+// nil.
+func (n *Node) TotalTime() time.Duration {
+	if n == nil {
+		return 0
+	}
+	return n.Duration
+}
+
+// TotalPomodoros returns the pomodoros of the group or zero if the node is
+// nil.
+func (n *Node) TotalPomodoros() int {
+	if n == nil {
+		return 0
+	}
+	return n.Pomodoros
+}
+
+// Notes returns the distinct non-empty notes of the entries of the node.
+func (n *Node) Notes() []string {
+	var notes []string
+	seen := make(map[string]bool)
+	for _, tt := range n.Entries {
+		if tt.Note == "" || seen[tt.Note] {
+			continue
+		}
+		seen[tt.Note] = true
+		notes = append(notes, tt.Note)
+	}
+	return notes
+}
+
+// Walk calls fn for every descendant of the node (but not the node itself),
+// parents before children. The path holds the ancestors of each node below
+// the starting one, followed by the node itself.
+func (n *Node) Walk(fn func(path []*Node)) {
+	var walk func(path []*Node, n *Node)
+	walk = func(path []*Node, n *Node) {
+		for _, c := range n.Children {
+			p := append(path[:len(path):len(path)], c)
+			fn(p)
+			walk(p, c)
+		}
+	}
+	walk(nil, n)
+}
+
+// Report is a tree of the time recorded on a board.
+type Report struct {
+	Options
+
+	// Root holds the total time of the report. Its children are the groups
+	// of the first level.
+	Root *Node
+}
+
+// DayKey returns the key of the day or week group that starts at the given
+// time.
+func DayKey(t time.Time) string {
+	return t.Format(DateFormat)
+}
+
+// part is the time of an entry within a single group of every level.
+type part struct {
+	list      *nonota.List
+	task      *nonota.Task
+	tt        *nonota.TaskTime
+	day       time.Time
+	duration  time.Duration
+	pomodoros int
+}
+
+// New builds the report of the time recorded on the board.
+//
+// Groups with no time recorded are not included. Lists and tasks are in the
+// order of the board, tags in alphabetical order (with untagged tasks last)
+// and days and weeks in chronological order.
+func New(b *nonota.Board, opts Options) *Report {
+	r := &Report{
+		Options: opts,
+		Root:    &Node{Title: "Total"},
+	}
+
+	byDay := false
+	for _, g := range opts.Groups {
+		byDay = byDay || g == GroupDay || g == GroupWeek
+	}
+
+	for _, l := range b.Lists {
+		for _, t := range l.Tasks {
+			if opts.Tag != "" && !t.HasTag(opts.Tag) {
+				continue
+			}
+			for _, tt := range t.Times {
+				for _, p := range r.split(tt, byDay) {
+					p.list, p.task = l, t
+					r.add(b, r.Root, 0, p)
+				}
+			}
+		}
+	}
+
+	r.Root.sort()
+	return r
+}
+
+// split returns the parts of the entry within the period of the report,
+// divided by day if byDay is true.
+func (r *Report) split(tt *nonota.TaskTime, byDay bool) []part {
+	from, to := r.From, r.To
+
+	// Pomodoros count in the period where the entry starts (see
+	// nonota.Task.PomodorosWithin).
+	pomodoros := func(start, end time.Time) int {
+		end = end.Truncate(time.Second).Add(time.Second)
+		if !tt.Start.Before(start) && tt.Start.Before(end) {
+			return tt.Pomodoros
+		}
+		return 0
+	}
+
+	loc := from.Location()
+	if !byDay || !tt.End.After(tt.Start) {
+		// Entries without a timeframe count fully on the day they
+		// start.
+		d := tt.DurationWithin(from, to)
+		if d <= 0 {
+			return nil
+		}
+		return []part{{
+			tt:        tt,
+			day:       nonota.StartOfDay(tt.Start.In(loc)),
+			duration:  d,
+			pomodoros: pomodoros(from, to),
+		}}
+	}
+
+	start, end := tt.Start, tt.End
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+
+	var parts []part
+	for day := nonota.StartOfDay(start.In(loc)); day.Before(end); day = nonota.StartOfDay(day.AddDate(0, 0, 1)) {
+		dayStart, dayEnd := day, nonota.EndOfDay(day)
+		if dayStart.Before(from) {
+			dayStart = from
+		}
+		if dayEnd.After(to) {
+			dayEnd = to
+		}
+		d := tt.DurationWithin(dayStart, dayEnd)
+		if d <= 0 {
+			continue
+		}
+		parts = append(parts, part{
+			tt:        tt,
+			day:       day,
+			duration:  d,
+			pomodoros: pomodoros(dayStart, dayEnd),
+		})
+	}
+	return parts
+}
+
+// add adds the part to the node and to its descendants at the given level.
+func (r *Report) add(b *nonota.Board, n *Node, level int, p part) {
+	n.Duration += p.duration
+	n.Pomodoros += p.pomodoros
+	if len(n.Entries) == 0 || n.Entries[len(n.Entries)-1] != p.tt {
+		n.Entries = append(n.Entries, p.tt)
+	}
+	if level >= len(r.Groups) {
+		return
+	}
+
+	group := r.Groups[level]
+	switch group {
+	case GroupList:
+		r.add(b, n.child(group, p.list.ID, func(c *Node) {
+			c.Title, c.List = p.list.Title, p.list
+		}), level+1, p)
+
+	case GroupTask:
+		r.add(b, n.child(group, p.task.ID, func(c *Node) {
+			c.Title, c.Task = p.task.Title, p.task
+		}), level+1, p)
+
+	case GroupTag:
+		tags := p.task.Tags
+		if len(tags) == 0 {
+			tags = []string{""}
+		}
+		for _, tag := range tags {
+			r.add(b, n.child(group, tag, func(c *Node) {
+				c.Tag, c.Title = tag, nonota.FormatTag(tag)
+				if tag == "" {
+					c.Title = "(untagged)"
+				}
+			}), level+1, p)
+		}
+
+	case GroupDay:
+		r.add(b, n.child(group, DayKey(p.day), func(c *Node) {
+			c.Start, c.End = p.day, nonota.EndOfDay(p.day)
+			c.Title = DayKey(p.day)
+		}), level+1, p)
+
+	case GroupWeek:
+		start := b.Settings.StartOfWeek(p.day)
+		r.add(b, n.child(group, DayKey(start), func(c *Node) {
+			c.Start, c.End = start, b.Settings.EndOfWeek(p.day)
+			// Weeks are labeled by the ISO week of their middle
+			// day, which holds most of their days even when they
+			// don't start on Monday.
+			c.Title = fmt.Sprintf("%s (week of %s)",
+				nonota.ISOWeek(start.AddDate(0, 0, 3)), DayKey(start))
+		}), level+1, p)
+
+	}
+}
+
+// child returns the child of the node with the given key, creating it with
+// init if needed.
+func (n *Node) child(group GroupBy, key string, init func(c *Node)) *Node {
+	if c, ok := n.children[key]; ok {
+		return c
+	}
+	c := &Node{Group: group, Key: key}
+	init(c)
+	if n.children == nil {
+		n.children = make(map[string]*Node)
+	}
+	n.children[key] = c
+	n.Children = append(n.Children, c)
+	return c
+}
+
+// sort sorts the children of the node and of its descendants. Lists and
+// tasks are kept in the order they were added, which is the order of the
+// board.
+func (n *Node) sort() {
+	sort.SliceStable(n.Children, func(i, j int) bool {
+		a, b := n.Children[i], n.Children[j]
+		switch a.Group {
+		case GroupTag:
+			if a.Tag == "" || b.Tag == "" {
+				return b.Tag == ""
+			}
+			return a.Tag < b.Tag
+		case GroupDay, GroupWeek:
+			return a.Start.Before(b.Start)
+		}
+		return false
+	})
+	for _, c := range n.Children {
+		c.sort()
+	}
+}
@@ -0,0 +1,234 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/matheusd/nonota"
+)
+
+func testBoard() *nonota.Board {
+	day := time.Date(2019, 3, 4, 0, 0, 0, 0, time.UTC)
+	entry := func(start, end, d time.Duration) *nonota.TaskTime {
+		return &nonota.TaskTime{
+			ID:       nonota.NewID(),
+			Start:    day.Add(start),
+			End:      day.Add(end),
+			Duration: d,
+		}
+	}
+	return &nonota.Board{
+		ID: nonota.NewID(),
+		Lists: []*nonota.List{{
+			ID:    nonota.NewID(),
+			Title: "Backlog",
+			Tasks: []*nonota.Task{{
+				ID:    nonota.NewID(),
+				Title: "Docs",
+				Tags:  []string{"docs", "billable"},
+				Times: []*nonota.TaskTime{
+					entry(10*time.Hour, 11*time.Hour, time.Hour),
+					// Crosses midnight, half on each day.
+					entry(23*time.Hour, 25*time.Hour, 2*time.Hour),
+				},
+			}, {
+				ID:    nonota.NewID(),
+				Title: "Idle",
+			}},
+		}, {
+			ID:    nonota.NewID(),
+			Title: "Done",
+			Tasks: []*nonota.Task{{
+				ID:    nonota.NewID(),
+				Title: "Bug",
+				Times: []*nonota.TaskTime{
+					// Manual entry without a timeframe.
+					entry(12*time.Hour, 12*time.Hour, 30*time.Minute),
+					// Next week (weeks start on Sunday).
+					entry(6*24*time.Hour, 6*24*time.Hour+time.Hour, time.Hour),
+				},
+			}},
+		}},
+	}
+}
+
+func TestReport(t *testing.T) {
+	b := testBoard()
+	from := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := nonota.EndOfDay(time.Date(2019, 3, 31, 0, 0, 0, 0, time.UTC))
+
+	r := New(b, Options{
+		From:   from,
+		To:     to,
+		Groups: []GroupBy{GroupList, GroupTask, GroupDay},
+	})
+	if r.Root.Duration != b.TotalTime(from, to) {
+		t.Fatalf("unexpected total %s", r.Root.Duration)
+	}
+	if len(r.Root.Children) != 2 {
+		t.Fatalf("unexpected number of lists %d", len(r.Root.Children))
+	}
+	backlog := r.Root.Child(b.Lists[0].ID)
+	if len(backlog.Children) != 1 || backlog.Duration != 3*time.Hour {
+		t.Fatalf("unexpected backlog node %#v", backlog)
+	}
+	docs := backlog.Child(b.Lists[0].Tasks[0].ID)
+	wantDays := map[string]time.Duration{
+		"2019-03-04": 2 * time.Hour,
+		"2019-03-05": time.Hour,
+	}
+	if len(docs.Children) != len(wantDays) {
+		t.Fatalf("unexpected number of days %d", len(docs.Children))
+	}
+	for key, d := range wantDays {
+		if n := docs.Child(key); n == nil || n.Duration != d {
+			t.Fatalf("unexpected total of day %s: %#v", key, n)
+		}
+	}
+	if len(docs.Entries) != 2 {
+		t.Fatalf("unexpected entries %v", docs.Entries)
+	}
+
+	// The period boundary splits entries the same way days do.
+	r = New(b, Options{
+		From:   from,
+		To:     nonota.EndOfDay(time.Date(2019, 3, 4, 0, 0, 0, 0, time.UTC)),
+		Groups: []GroupBy{GroupDay},
+	})
+	if r.Root.Duration != 150*time.Minute || len(r.Root.Children) != 1 {
+		t.Fatalf("unexpected total %s", r.Root.Duration)
+	}
+
+	// Tags count tasks once per tag, but the parents count them once.
+	r = New(b, Options{
+		From:   from,
+		To:     to,
+		Groups: []GroupBy{GroupWeek, GroupTag},
+	})
+	if r.Root.Duration != 270*time.Minute || len(r.Root.Children) != 2 {
+		t.Fatalf("unexpected weeks %#v", r.Root)
+	}
+	week := r.Root.Children[0]
+	if week.Key != "2019-03-03" || week.Duration != 210*time.Minute ||
+		week.Title != "2019-W10 (week of 2019-03-03)" {
+		t.Fatalf("unexpected first week %#v", week)
+	}
+	var tags []string
+	for _, c := range week.Children {
+		tags = append(tags, c.Title)
+	}
+	if len(tags) != 3 || tags[0] != "#billable" || tags[1] != "#docs" ||
+		tags[2] != "(untagged)" {
+		t.Fatalf("unexpected tags %v", tags)
+	}
+	if week.Children[0].Duration != 3*time.Hour {
+		t.Fatalf("unexpected tag total %s", week.Children[0].Duration)
+	}
+
+	// Only tasks with the tag.
+	r = New(b, Options{From: from, To: to, Tag: "docs"})
+	if r.Root.Duration != 3*time.Hour || len(r.Root.Children) != 0 {
+		t.Fatalf("unexpected tag report %#v", r.Root)
+	}
+}
+
+func TestWeekTitles(t *testing.T) {
+	// ISO weeks may belong to the previous or next year.
+	tests := []struct {
+		weekStart time.Weekday
+		day       time.Time
+		title     string
+	}{
+		{time.Monday, time.Date(2018, 12, 31, 0, 0, 0, 0, time.UTC),
+			"2019-W01 (week of 2018-12-31)"},
+		{time.Sunday, time.Date(2019, 12, 31, 0, 0, 0, 0, time.UTC),
+			"2020-W01 (week of 2019-12-29)"},
+		{time.Monday, time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC),
+			"2020-W53 (week of 2020-12-28)"},
+	}
+	for _, tc := range tests {
+		b := &nonota.Board{
+			Settings: nonota.Settings{WeekStart: nonota.Weekday(tc.weekStart)},
+			Lists: []*nonota.List{{Tasks: []*nonota.Task{{
+				Times: []*nonota.TaskTime{{
+					Start:    tc.day.Add(10 * time.Hour),
+					End:      tc.day.Add(11 * time.Hour),
+					Duration: time.Hour,
+				}},
+			}}}},
+		}
+		r := New(b, Options{
+			From:   nonota.StartOfDay(tc.day),
+			To:     nonota.EndOfDay(tc.day),
+			Groups: []GroupBy{GroupWeek},
+		})
+		if len(r.Root.Children) != 1 || r.Root.Children[0].Title != tc.title {
+			t.Fatalf("unexpected weeks of %s: %#v", tc.day, r.Root.Children)
+		}
+	}
+}
+
+func TestParseGroups(t *testing.T) {
+	groups, err := ParseGroups("list>Task, day")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 3 || groups[0] != GroupList || groups[1] != GroupTask ||
+		groups[2] != GroupDay {
+		t.Fatalf("unexpected groups %v", groups)
+	}
+	if _, err := ParseGroups("list,month"); err == nil {
+		t.Fatalf("expected error parsing unknown group")
+	}
+}
+
+func TestRenderCSV(t *testing.T) {
+	b := testBoard()
+	b.Lists[0].Tasks[0].Title = `Docs, "quoted"`
+	r := New(b, Options{
+		From:   time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:     time.Date(2019, 3, 5, 23, 59, 59, 0, time.UTC),
+		Groups: []GroupBy{GroupList, GroupTask},
+	})
+
+	var buf bytes.Buffer
+	if err := (CSVRenderer{Header: true}).Render(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	want := "list,task,hours\n" +
+		"Backlog,\"Docs, \"\"quoted\"\"\",3.00\n" +
+		"Done,Bug,0.50\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected csv:\n%s", buf.String())
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	b := testBoard()
+	docs := b.Lists[0].Tasks[0]
+	docs.Title = "Docs | guide"
+	docs.Description = "First line\n\nSecond *line*"
+	docs.Times[0].Note = "draft"
+	r := New(b, Options{
+		From:   time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:     time.Date(2019, 3, 5, 23, 59, 59, 0, time.UTC),
+		Groups: []GroupBy{GroupList, GroupTask},
+	})
+
+	var buf bytes.Buffer
+	if err := (MarkdownRenderer{}).Render(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	want := "# Timesheet: 2019-03-01 to 2019-03-05\n\n" +
+		"| Item | Details | Hours |\n" +
+		"| --- | --- | ---: |\n" +
+		"| **Backlog** | | **3.00** |\n" +
+		"| &nbsp;&nbsp;Docs \\| guide | First line<br>Second \\*line\\*<br>• draft | 3.00 |\n" +
+		"| **Done** | | **0.50** |\n" +
+		"| &nbsp;&nbsp;Bug |  | 0.50 |\n" +
+		"| **Total** | | **3.50** |\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected markdown:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,631 @@
+// Package sqlite implements a nonota storage backend on top of an SQLite
+// database, using a pure-Go SQLite implementation.
+//
+// Importing this package registers the backend for board files with the .db,
+// .sqlite and .sqlite3 extensions.
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/matheusd/nonota"
+	yaml "gopkg.in/yaml.v2"
+
+	// Registers the sqlite database/sql driver.
+	_ "modernc.org/sqlite"
+)
+
+// schemaVersion is the version of the database schema, stored in the
+// user_version pragma of the database.
+const schemaVersion = 5
+
+// schema are the statements that create the database, indexed by the version
+// they upgrade from.
+var schema = []string{
+	0: `
+	CREATE TABLE board (
+		id TEXT PRIMARY KEY
+	);
+
+	CREATE TABLE lists (
+		id TEXT PRIMARY KEY,
+		position INTEGER NOT NULL,
+		title TEXT NOT NULL
+	);
+
+	CREATE TABLE tasks (
+		id TEXT PRIMARY KEY,
+		list_id TEXT NOT NULL REFERENCES lists(id),
+		position INTEGER NOT NULL,
+		title TEXT NOT NULL,
+		description TEXT NOT NULL
+	);
+	CREATE INDEX tasks_list_id ON tasks(list_id);
+
+	CREATE TABLE task_times (
+		id TEXT PRIMARY KEY,
+		task_id TEXT NOT NULL REFERENCES tasks(id),
+		position INTEGER NOT NULL,
+		start TEXT NOT NULL,
+		end TEXT NOT NULL,
+		note TEXT NOT NULL,
+		duration INTEGER NOT NULL
+	);
+	CREATE INDEX task_times_task_id ON task_times(task_id);
+	`,
+
+	1: `
+	ALTER TABLE lists ADD COLUMN archived INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE tasks ADD COLUMN archived INTEGER NOT NULL DEFAULT 0;
+	`,
+
+	2: `
+	CREATE TABLE task_tags (
+		task_id TEXT NOT NULL REFERENCES tasks(id),
+		position INTEGER NOT NULL,
+		tag TEXT NOT NULL,
+		PRIMARY KEY (task_id, tag)
+	);
+	`,
+
+	3: `
+	ALTER TABLE board ADD COLUMN settings TEXT NOT NULL DEFAULT '';
+	`,
+
+	4: `
+	ALTER TABLE task_times ADD COLUMN pomodoros INTEGER NOT NULL DEFAULT 0;
+	`,
+}
+
+// timeFormat is the format times are stored in. It keeps the offset of the
+// original time, so that it's reproduced when loaded.
+const timeFormat = time.RFC3339Nano
+
+// Storage stores boards in an SQLite database. Unlike the yaml storage, it
+// only writes what changed on incremental operations.
+//
+// Like the yaml storage, it holds the board lock (see nonota.LockBoard) while
+// reading or writing the database and backs up the database before writing to
+// it, as configured by the Backups option.
+type Storage struct {
+	db       *sql.DB
+	filename string
+	backups  nonota.BackupTimer
+}
+
+// Open opens (creating if needed) the SQLite database at the given filename.
+func Open(filename string, opts nonota.StorageOptions) (*Storage, error) {
+	db, err := sql.Open("sqlite", filename)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite only supports a single writer, so avoid "database is locked"
+	// errors between connections of this same process.
+	db.SetMaxOpenConns(1)
+
+	s := &Storage{
+		db:       db,
+		filename: filename,
+		backups:  nonota.BackupTimer{Keep: opts.Backups},
+	}
+	if err := s.upgradeSchema(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func init() {
+	nonota.RegisterStorage("sqlite", []string{".db", ".sqlite", ".sqlite3"},
+		func(filename string, opts nonota.StorageOptions) (nonota.Storage, error) {
+			return Open(filename, opts)
+		})
+}
+
+// version returns the schema version of the database.
+func (s *Storage) version() (int, error) {
+	var version int
+	err := s.db.QueryRow("PRAGMA user_version").Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	if version > schemaVersion {
+		return 0, fmt.Errorf("database version %d is newer than the latest "+
+			"supported version %d; upgrade nonota to open it", version,
+			schemaVersion)
+	}
+	return version, nil
+}
+
+func (s *Storage) upgradeSchema() error {
+	version, err := s.version()
+	if err != nil || version == schemaVersion {
+		return err
+	}
+
+	lock, err := nonota.LockBoard(s.filename, true)
+	if err != nil {
+		return fmt.Errorf("unable to lock %s: %v", s.filename, err)
+	}
+	defer lock.Unlock()
+
+	// Some other process may have upgraded the database while waiting for
+	// the lock.
+	if version, err = s.version(); err != nil {
+		return err
+	}
+
+	for ; version < schemaVersion; version++ {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(schema[version]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error upgrading database from version "+
+				"%d: %v", version, err)
+		}
+		// Pragmas can't use placeholders.
+		pragma := fmt.Sprintf("PRAGMA user_version = %d", version+1)
+		if _, err := tx.Exec(pragma); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lockWrite acquires the exclusive board lock and, if one is due, backs up the
+// database before it's written to. The returned lock must be released after
+// the write.
+func (s *Storage) lockWrite() (*nonota.FileLock, error) {
+	lock, err := nonota.LockBoard(s.filename, true)
+	if err != nil {
+		return nil, fmt.Errorf("unable to lock %s: %v", s.filename, err)
+	}
+
+	// The database is modified in place, so it can't be hard linked like
+	// yaml files. VACUUM INTO writes a consistent copy of it instead.
+	keep := s.backups.Due(time.Now())
+	err = nonota.BackupFileWith(s.filename, keep, func(backup string) error {
+		_, err := s.db.Exec("VACUUM INTO ?", backup)
+		return err
+	})
+	if err != nil {
+		lock.Unlock()
+		return nil, err
+	}
+	return lock, nil
+}
+
+// Load is part of the nonota.Storage interface.
+func (s *Storage) Load() (*nonota.Board, error) {
+	lock, err := nonota.LockBoard(s.filename, false)
+	if err != nil {
+		return nil, fmt.Errorf("unable to lock %s: %v", s.filename, err)
+	}
+	defer lock.Unlock()
+
+	b := &nonota.Board{Version: nonota.SchemaVersion}
+	var settings string
+	err = s.db.QueryRow("SELECT id, settings FROM board").Scan(&b.ID, &settings)
+	if err == sql.ErrNoRows {
+		b.ID = nonota.NewID()
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Settings are stored as yaml, in the same format as in board files.
+	if err := yaml.Unmarshal([]byte(settings), &b.Settings); err != nil {
+		return nil, fmt.Errorf("error decoding board settings: %v", err)
+	}
+	if err := b.Settings.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid board settings: %v", err)
+	}
+
+	lists := make(map[string]*nonota.List)
+	rows, err := s.db.Query("SELECT id, title, archived FROM lists " +
+		"ORDER BY position")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		l := &nonota.List{}
+		if err := rows.Scan(&l.ID, &l.Title, &l.Archived); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		lists[l.ID] = l
+		b.Lists = append(b.Lists, l)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	tasks := make(map[string]*nonota.Task)
+	rows, err = s.db.Query("SELECT id, list_id, title, description, " +
+		"archived FROM tasks ORDER BY list_id, position")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		t := &nonota.Task{}
+		var listID string
+		err := rows.Scan(&t.ID, &listID, &t.Title, &t.Description, &t.Archived)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		l, ok := lists[listID]
+		if !ok {
+			rows.Close()
+			return nil, fmt.Errorf("task %s references unknown list %s",
+				t.ID, listID)
+		}
+		tasks[t.ID] = t
+		l.Tasks = append(l.Tasks, t)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.Query("SELECT task_id, tag FROM task_tags " +
+		"ORDER BY task_id, position")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var taskID, tag string
+		if err := rows.Scan(&taskID, &tag); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		t, ok := tasks[taskID]
+		if !ok {
+			rows.Close()
+			return nil, fmt.Errorf("tag %s references unknown task %s",
+				tag, taskID)
+		}
+		t.Tags = append(t.Tags, tag)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.Query("SELECT id, task_id, start, end, note, duration, " +
+		"pomodoros FROM task_times ORDER BY task_id, position")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		tt := &nonota.TaskTime{}
+		var taskID, start, end string
+		var duration int64
+		err := rows.Scan(&tt.ID, &taskID, &start, &end, &tt.Note, &duration,
+			&tt.Pomodoros)
+		if err != nil {
+			return nil, err
+		}
+		if tt.Start, err = time.Parse(timeFormat, start); err != nil {
+			return nil, err
+		}
+		if tt.End, err = time.Parse(timeFormat, end); err != nil {
+			return nil, err
+		}
+		tt.Duration = time.Duration(duration)
+
+		t, ok := tasks[taskID]
+		if !ok {
+			return nil, fmt.Errorf("time entry %s references unknown "+
+				"task %s", tt.ID, taskID)
+		}
+		t.Times = append(t.Times, tt)
+	}
+
+	return b, rows.Err()
+}
+
+// Save is part of the nonota.Storage interface. Rows are updated in place
+// (and only if they changed), and the rows of items no longer in the board are
+// deleted.
+func (s *Storage) Save(b *nonota.Board) error {
+	lock, err := s.lockWrite()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := saveBoard(tx, b); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func saveBoard(tx *sql.Tx, b *nonota.Board) error {
+	settings, err := yaml.Marshal(&b.Settings)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM board WHERE id != ?", b.ID); err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO board (id, settings) VALUES (?, ?) "+
+		"ON CONFLICT (id) DO UPDATE SET settings = excluded.settings "+
+		"WHERE settings IS NOT excluded.settings", b.ID, string(settings))
+	if err != nil {
+		return err
+	}
+
+	tags, err := loadTags(tx)
+	if err != nil {
+		return err
+	}
+
+	lists := make(map[string]bool)
+	tasks := make(map[string]bool)
+	times := make(map[string]bool)
+	for i, l := range b.Lists {
+		lists[l.ID] = true
+		_, err := tx.Exec("INSERT INTO lists (id, position, title, "+
+			"archived) VALUES (?, ?, ?, ?) "+
+			"ON CONFLICT (id) DO UPDATE SET position = excluded.position, "+
+			"title = excluded.title, archived = excluded.archived "+
+			"WHERE (position, title, archived) IS NOT "+
+			"(excluded.position, excluded.title, excluded.archived)",
+			l.ID, i, l.Title, l.Archived)
+		if err != nil {
+			return err
+		}
+
+		for j, t := range l.Tasks {
+			tasks[t.ID] = true
+			_, err := tx.Exec("INSERT INTO tasks (id, list_id, "+
+				"position, title, description, archived) "+
+				"VALUES (?, ?, ?, ?, ?, ?) "+
+				"ON CONFLICT (id) DO UPDATE SET "+
+				"list_id = excluded.list_id, "+
+				"position = excluded.position, title = excluded.title, "+
+				"description = excluded.description, "+
+				"archived = excluded.archived "+
+				"WHERE (list_id, position, title, description, "+
+				"archived) IS NOT (excluded.list_id, "+
+				"excluded.position, excluded.title, "+
+				"excluded.description, excluded.archived)",
+				t.ID, l.ID, j, t.Title, t.Description, t.Archived)
+			if err != nil {
+				return err
+			}
+			if !equalTags(tags[t.ID], t.Tags) {
+				if err := replaceTags(tx, t); err != nil {
+					return err
+				}
+			}
+
+			for k, tt := range t.Times {
+				times[tt.ID] = true
+				if err := upsertTaskTime(tx, t, k, tt); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// Delete the rows of removed items, referencing rows first.
+	if err := deleteMissing(tx, "task_times", "id", times); err != nil {
+		return err
+	}
+	if err := deleteMissing(tx, "task_tags", "task_id", tasks); err != nil {
+		return err
+	}
+	if err := deleteMissing(tx, "tasks", "id", tasks); err != nil {
+		return err
+	}
+	return deleteMissing(tx, "lists", "id", lists)
+}
+
+// loadTags returns the stored tags of every task, indexed by task id.
+func loadTags(tx *sql.Tx) (map[string][]string, error) {
+	rows, err := tx.Query("SELECT task_id, tag FROM task_tags " +
+		"ORDER BY task_id, position")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tags := make(map[string][]string)
+	for rows.Next() {
+		var taskID, tag string
+		if err := rows.Scan(&taskID, &tag); err != nil {
+			return nil, err
+		}
+		tags[taskID] = append(tags[taskID], tag)
+	}
+	return tags, rows.Err()
+}
+
+func equalTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// deleteMissing deletes the rows of table whose column (an id) is not one of
+// the given ids.
+func deleteMissing(tx *sql.Tx, table, column string, ids map[string]bool) error {
+	rows, err := tx.Query("SELECT DISTINCT " + column + " FROM " + table)
+	if err != nil {
+		return err
+	}
+	var missing []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		if !ids[id] {
+			missing = append(missing, id)
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	for _, id := range missing {
+		_, err := tx.Exec("DELETE FROM "+table+" WHERE "+column+" = ?", id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func upsertTaskTime(tx *sql.Tx, task *nonota.Task, position int, tt *nonota.TaskTime) error {
+	_, err := tx.Exec("INSERT INTO task_times (id, task_id, position, "+
+		"start, end, note, duration, pomodoros) "+
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?) "+
+		"ON CONFLICT (id) DO UPDATE SET task_id = excluded.task_id, "+
+		"position = excluded.position, start = excluded.start, "+
+		"end = excluded.end, note = excluded.note, "+
+		"duration = excluded.duration, pomodoros = excluded.pomodoros "+
+		"WHERE (task_id, position, start, end, note, duration, "+
+		"pomodoros) IS NOT (excluded.task_id, excluded.position, "+
+		"excluded.start, excluded.end, excluded.note, "+
+		"excluded.duration, excluded.pomodoros)",
+		tt.ID, task.ID, position, tt.Start.Format(timeFormat),
+		tt.End.Format(timeFormat), tt.Note, int64(tt.Duration), tt.Pomodoros)
+	return err
+}
+
+// execer is satisfied by both sql.DB and sql.Tx.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func insertTaskTime(db execer, task *nonota.Task, position int, tt *nonota.TaskTime) error {
+	_, err := db.Exec("INSERT INTO task_times (id, task_id, position, "+
+		"start, end, note, duration, pomodoros) "+
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		tt.ID, task.ID, position, tt.Start.Format(timeFormat),
+		tt.End.Format(timeFormat), tt.Note, int64(tt.Duration), tt.Pomodoros)
+	return err
+}
+
+// replaceTags replaces the stored tags of the given task.
+func replaceTags(db execer, task *nonota.Task) error {
+	_, err := db.Exec("DELETE FROM task_tags WHERE task_id = ?", task.ID)
+	if err != nil {
+		return err
+	}
+	for i, tag := range task.Tags {
+		_, err := db.Exec("INSERT INTO task_tags (task_id, position, tag) "+
+			"VALUES (?, ?, ?)", task.ID, i, tag)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkUpdated returns an error if the given update did not affect any rows.
+func checkUpdated(res sql.Result, what, id string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("%s %s not found in the database", what, id)
+	}
+	return nil
+}
+
+// AppendTaskTime is part of the nonota.Storage interface.
+func (s *Storage) AppendTaskTime(task *nonota.Task, tt *nonota.TaskTime) error {
+	lock, err := s.lockWrite()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	position := len(task.Times)
+	for i, other := range task.Times {
+		if other == tt {
+			position = i
+			break
+		}
+	}
+	return insertTaskTime(s.db, task, position, tt)
+}
+
+// UpdateTask is part of the nonota.Storage interface.
+func (s *Storage) UpdateTask(task *nonota.Task) error {
+	lock, err := s.lockWrite()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec("UPDATE tasks SET title = ?, description = ?, "+
+		"archived = ? WHERE id = ?", task.Title, task.Description,
+		task.Archived, task.ID)
+	if err == nil {
+		err = checkUpdated(res, "task", task.ID)
+	}
+	if err == nil {
+		err = replaceTags(tx, task)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateList is part of the nonota.Storage interface.
+func (s *Storage) UpdateList(list *nonota.List) error {
+	lock, err := s.lockWrite()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	res, err := s.db.Exec("UPDATE lists SET title = ?, archived = ? "+
+		"WHERE id = ?", list.Title, list.Archived, list.ID)
+	if err != nil {
+		return err
+	}
+	return checkUpdated(res, "list", list.ID)
+}
+
+// Close is part of the nonota.Storage interface.
+func (s *Storage) Close() error {
+	return s.db.Close()
+}
+
+var _ nonota.Storage = (*Storage)(nil)
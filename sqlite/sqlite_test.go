@@ -0,0 +1,289 @@
+package sqlite
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/matheusd/nonota"
+)
+
+func testBoard() *nonota.Board {
+	brt := time.FixedZone("", -3*60*60)
+	start := time.Date(2019, 3, 1, 10, 0, 0, 0, brt)
+	return &nonota.Board{
+		Version: nonota.SchemaVersion,
+		ID:      nonota.NewID(),
+		Settings: nonota.Settings{
+			Billing: nonota.BillingPeriod{
+				Kind:   nonota.BillingBiWeekly,
+				Anchor: "2019-01-07",
+			},
+			WeekStart: nonota.Weekday(time.Monday),
+		},
+		Lists: []*nonota.List{{
+			ID:    nonota.NewID(),
+			Title: "Backlog",
+			Tasks: []*nonota.Task{{
+				ID:          nonota.NewID(),
+				Title:       "First #one",
+				Description: "Some\ndescription",
+				Tags:        []string{"one", "two"},
+				Times: []*nonota.TaskTime{{
+					ID:        nonota.NewID(),
+					Start:     start,
+					End:       start.Add(time.Hour),
+					Note:      "note",
+					Duration:  50 * time.Minute,
+					Pomodoros: 2,
+				}},
+			}, {
+				ID:    nonota.NewID(),
+				Title: "Second",
+			}},
+		}, {
+			ID:       nonota.NewID(),
+			Title:    "Done",
+			Archived: true,
+		}},
+	}
+}
+
+func TestStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nonota")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fname := filepath.Join(dir, "board.db")
+
+	s, err := Open(fname, nonota.StorageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// An empty database returns an empty board.
+	b, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.ID == "" || len(b.Lists) != 0 {
+		t.Fatalf("unexpected empty board %#v", b)
+	}
+
+	b = testBoard()
+	if err := s.Save(b); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(b, loaded) {
+		t.Fatalf("loaded board differs from saved one")
+	}
+
+	// Incremental operations.
+	task := b.Lists[0].Tasks[1]
+	task.Title = "Second (edited)"
+	task.Archived = true
+	task.Tags = []string{"three"}
+	if err := s.UpdateTask(task); err != nil {
+		t.Fatal(err)
+	}
+	b.Lists[1].Title = "Done (edited)"
+	if err := s.UpdateList(b.Lists[1]); err != nil {
+		t.Fatal(err)
+	}
+	tt := &nonota.TaskTime{
+		ID:       nonota.NewID(),
+		Start:    time.Date(2019, 3, 2, 10, 0, 0, 0, time.UTC),
+		End:      time.Date(2019, 3, 2, 11, 0, 0, 0, time.UTC),
+		Duration: time.Hour,
+	}
+	task.AddTaskTime(tt)
+	if err := s.AppendTaskTime(task, tt); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdateTask(&nonota.Task{ID: "unknown"}); err == nil {
+		t.Fatalf("expected error updating unknown task")
+	}
+
+	// Reopen the database to ensure everything was persisted.
+	s.Close()
+	s, err = Open(fname, nonota.StorageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err = s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(b, loaded) {
+		t.Fatalf("loaded board differs after incremental operations")
+	}
+}
+
+func TestSaveInPlace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nonota")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fname := filepath.Join(dir, "board.db")
+
+	s, err := Open(fname, nonota.StorageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	b := testBoard()
+	if err := s.Save(b); err != nil {
+		t.Fatal(err)
+	}
+
+	// Saving the same board again doesn't change any rows.
+	changes := func() int {
+		var n int
+		if err := s.db.QueryRow("SELECT total_changes()").Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	before := changes()
+	if err := s.Save(b); err != nil {
+		t.Fatal(err)
+	}
+	if n := changes() - before; n != 0 {
+		t.Fatalf("unexpected %d changes saving an unchanged board", n)
+	}
+
+	// Removed items are deleted and changed ones updated.
+	task := b.Lists[0].Tasks[0]
+	task.Tags = []string{"two"}
+	task.Times[0].Note = "edited"
+	b.Lists[0].Tasks = b.Lists[0].Tasks[:1]
+	b.Lists = b.Lists[:1]
+	before = changes()
+	if err := s.Save(b); err != nil {
+		t.Fatal(err)
+	}
+	// The list, task and time entry that were removed, the tags of the
+	// task (deleted and reinserted) and the edited entry.
+	if n := changes() - before; n != 6 {
+		t.Fatalf("unexpected %d changes saving the edited board", n)
+	}
+	loaded, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(b, loaded) {
+		t.Fatalf("loaded board differs from saved one")
+	}
+	for _, table := range []string{"lists", "tasks", "task_times"} {
+		var n int
+		if err := s.db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		if n != 1 {
+			t.Fatalf("unexpected %d rows in %s", n, table)
+		}
+	}
+}
+
+func TestStorageBackups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nonota")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fname := filepath.Join(dir, "board.db")
+
+	b := testBoard()
+	s, err := Open(fname, nonota.StorageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save(b); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// Only the first write of a session backs up the database.
+	s, err = Open(fname, nonota.StorageOptions{Backups: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	list := b.Lists[0]
+	list.Title = "Edited"
+	if err := s.UpdateList(list); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save(b); err != nil {
+		t.Fatal(err)
+	}
+	backups, err := nonota.ListBackups(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("unexpected backups %v", backups)
+	}
+
+	// The backup is a database with the board before the session.
+	bs, err := Open(backups[0], nonota.StorageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bs.Close()
+	backup, err := bs.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backup.Lists[0].Title != "Backlog" {
+		t.Fatalf("unexpected list in backup %q", backup.Lists[0].Title)
+	}
+}
+
+func TestStorageLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nonota")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fname := filepath.Join(dir, "board.db")
+
+	s, err := Open(fname, nonota.StorageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// Writes wait for the board lock held by other writers.
+	lock, err := nonota.LockBoard(fname, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- s.Save(testBoard()) }()
+	select {
+	case err := <-done:
+		t.Fatalf("save did not wait for the lock (%v)", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	lock.Unlock()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("save did not finish after releasing the lock")
+	}
+}
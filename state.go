@@ -0,0 +1,74 @@
+package nonota
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// WorkState is the persisted state of an in-progress (possibly paused) work,
+// so that it can be recreated after nonota is restarted.
+type WorkState struct {
+	TaskID   string
+	TimeID   string
+	Start    time.Time
+	Duration time.Duration
+	Note     string
+	Paused   bool
+
+	// Pomodoros is the number of pomodoros completed in the work.
+	Pomodoros int `yaml:",omitempty"`
+
+	// SavedAt is when the state was saved. For works that were running,
+	// the time between SavedAt and the restart was not tracked.
+	SavedAt time.Time
+}
+
+// Gap returns how much time passed since the state was saved, up to the given
+// time. Paused works have no gap, since they wouldn't be accumulating time.
+func (s *WorkState) Gap(now time.Time) time.Duration {
+	if s.Paused || now.Before(s.SavedAt) {
+		return 0
+	}
+	return now.Sub(s.SavedAt)
+}
+
+// WorksFilename returns the name of the file that stores the state of the
+// in-progress works of the given board file.
+func WorksFilename(boardFilename string) string {
+	return boardFilename + ".works.yml"
+}
+
+// WorksToFile atomically saves the given work states to a file.
+func WorksToFile(filename string, states []WorkState) error {
+	return writeFileAtomic(filename, func(w io.Writer) error {
+		enc := yaml.NewEncoder(w)
+		if err := enc.Encode(states); err != nil {
+			return err
+		}
+		return enc.Close()
+	})
+}
+
+// WorksFromFile loads the work states stored in the given file. A missing file
+// is not an error, given that there may simply not be any in-progress works.
+func WorksFromFile(filename string) ([]WorkState, error) {
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var states []WorkState
+	err = yaml.NewDecoder(f).Decode(&states)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("error decoding file %s: %v", filename, err)
+	}
+	return states, nil
+}
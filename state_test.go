@@ -0,0 +1,111 @@
+package nonota
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWorkStatePersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nonota")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fname := WorksFilename(filepath.Join(dir, "board.yml"))
+
+	// A missing file means there are no works.
+	states, err := WorksFromFile(fname)
+	if err != nil || len(states) != 0 {
+		t.Fatalf("unexpected states %v (%v)", states, err)
+	}
+
+	start := time.Date(2019, 3, 20, 10, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+	taskA := &Task{ID: NewID()}
+	taskB := &Task{ID: NewID()}
+	taskC := &Task{ID: NewID()}
+	board := &Board{Lists: []*List{{Tasks: []*Task{taskA, taskB, taskC}}}}
+	user := &User{Clock: clock}
+
+	workB := user.ToggleWorkOnTask(taskB)
+	workB.SetNote("paused")
+	workB.CompletePomodoro()
+	clock.Advance(10 * time.Minute)
+	workA := user.ToggleWorkOnTask(taskA)
+	clock.Advance(5 * time.Minute)
+
+	if err := WorksToFile(fname, user.WorkStates()); err != nil {
+		t.Fatal(err)
+	}
+	states, err = WorksFromFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(states) != 2 {
+		t.Fatalf("unexpected states %v", states)
+	}
+	stB, stA := states[0], states[1]
+	if stB.TaskID != taskB.ID || stB.TimeID != workB.TaskTime().ID ||
+		!stB.Paused || stB.Duration != 11*time.Minute ||
+		stB.Note != "paused" || stB.Pomodoros != 1 {
+		t.Fatalf("unexpected state of work B %#v", stB)
+	}
+	if stA.TaskID != taskA.ID || stA.TimeID != workA.TaskTime().ID ||
+		stA.Paused || stA.Duration != 6*time.Minute ||
+		!stA.Start.Equal(start.Add(10*time.Minute)) ||
+		!stA.SavedAt.Equal(clock.Now()) {
+		t.Fatalf("unexpected state of work A %#v", stA)
+	}
+
+	// The time between saving and restoring is only a gap for running
+	// works.
+	clock.Advance(20 * time.Minute)
+	if gap := stA.Gap(clock.Now()); gap != 20*time.Minute {
+		t.Fatalf("unexpected gap of work A %s", gap)
+	}
+	if gap := stB.Gap(clock.Now()); gap != 0 {
+		t.Fatalf("unexpected gap of work B %s", gap)
+	}
+	if gap := stA.Gap(start); gap != 0 {
+		t.Fatalf("unexpected gap before saving %s", gap)
+	}
+
+	// States of removed tasks, of works already recorded in the board and
+	// of tasks with a work are skipped.
+	recorded := WorkState{TaskID: taskC.ID, TimeID: NewID()}
+	taskC.AddTaskTime(&TaskTime{ID: recorded.TimeID})
+	states = append(states,
+		WorkState{TaskID: NewID()},
+		recorded,
+		WorkState{TaskID: taskA.ID, TimeID: NewID()})
+	restored := &User{Clock: clock}
+	works := restored.RestoreWorks(board, states)
+	if len(works) != len(states) || len(restored.CurrentWorks()) != 2 ||
+		works[2] != nil || works[3] != nil || works[4] != nil {
+		t.Fatalf("unexpected restored works %v", works)
+	}
+
+	// Restored works keep their entry and continue from their state (the
+	// gap is handled by the caller).
+	rB, rA := works[0], works[1]
+	if rB.Task() != taskB || !rB.IsPaused() || rB.TaskTime().ID != stB.TimeID ||
+		rB.TaskTime().Note != "paused" || rB.Pomodoros() != 1 {
+		t.Fatalf("unexpected restored work B %#v", rB.State())
+	}
+	clock.Advance(time.Minute)
+	if d := rB.CurrentDuration(); d != 11*time.Minute {
+		t.Fatalf("unexpected duration of restored work B %s", d)
+	}
+	if rA.Task() != taskA || rA.IsPaused() || rA.CurrentDuration() != 7*time.Minute {
+		t.Fatalf("unexpected restored work A %#v", rA.State())
+	}
+	if err := restored.StopWork(rA); err != nil {
+		t.Fatal(err)
+	}
+	if tt := taskA.TaskTimeByID(stA.TimeID); tt == nil || tt.Duration != 7*time.Minute {
+		t.Fatalf("unexpected time entry of restored work A %v", taskA.Times)
+	}
+}
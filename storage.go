@@ -0,0 +1,163 @@
+package nonota
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Storage is the interface of the backends that persist boards.
+//
+// Besides loading and saving whole boards, storages offer incremental
+// operations for the most common changes. Those are called after the change
+// was applied to the in-memory board, and backends that can't do better may
+// simply save the whole board again.
+type Storage interface {
+	// Load loads the stored board. Storages without a board yet return a
+	// new, empty one.
+	Load() (*Board, error)
+
+	// Save replaces the stored board with the given one.
+	Save(b *Board) error
+
+	// AppendTaskTime persists a new time entry of the given task.
+	AppendTaskTime(task *Task, tt *TaskTime) error
+
+	// UpdateTask persists the changed fields of the given task (but not
+	// its time entries).
+	UpdateTask(task *Task) error
+
+	// UpdateList persists the changed fields of the given list (but not
+	// its tasks).
+	UpdateList(list *List) error
+
+	// Close releases the resources used by the storage.
+	Close() error
+}
+
+// StorageOptions are the options used when opening a storage. Not every
+// option is supported by every backend.
+type StorageOptions struct {
+	// Backups is the number of backups of the previous versions of the
+	// board to keep. Backups are taken on the first save and then at most
+	// once every BackupInterval.
+	Backups int
+}
+
+// StorageOpener opens the storage for the board at the given filename.
+type StorageOpener func(filename string, opts StorageOptions) (Storage, error)
+
+type storageBackend struct {
+	name string
+	exts []string
+	open StorageOpener
+}
+
+var (
+	storageBackendsMtx sync.Mutex
+	storageBackends    []storageBackend
+)
+
+// RegisterStorage registers a storage backend, to be used to open board files
+// with any of the given extensions (including the dot, such as ".db").
+// Backends are usually registered by their package's init function.
+func RegisterStorage(name string, exts []string, open StorageOpener) {
+	storageBackendsMtx.Lock()
+	storageBackends = append(storageBackends, storageBackend{name, exts, open})
+	storageBackendsMtx.Unlock()
+}
+
+// OpenStorage opens the storage for the given board file. The backend is
+// selected by the file extension. Files not handled by any of the registered
+// backends are stored as yaml.
+func OpenStorage(filename string, opts StorageOptions) (Storage, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+
+	storageBackendsMtx.Lock()
+	var open StorageOpener
+	for _, backend := range storageBackends {
+		for _, e := range backend.exts {
+			if e == ext {
+				open = backend.open
+			}
+		}
+	}
+	storageBackendsMtx.Unlock()
+
+	if open == nil {
+		return NewYAMLStorage(filename, opts.Backups), nil
+	}
+
+	s, err := open(filename, opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open storage %s: %v", filename, err)
+	}
+	return s, nil
+}
+
+// YAMLStorage stores boards in a yaml file, which is fully rewritten on every
+// change.
+type YAMLStorage struct {
+	filename string
+	backups  BackupTimer
+
+	// board is the last loaded or saved board, which gets saved by the
+	// incremental operations.
+	board *Board
+}
+
+// NewYAMLStorage returns a storage for the given yaml file, keeping the given
+// number of backups of the file when saving (see BackupInterval).
+func NewYAMLStorage(filename string, backups int) *YAMLStorage {
+	return &YAMLStorage{
+		filename: filename,
+		backups:  BackupTimer{Keep: backups},
+	}
+}
+
+// Load is part of the Storage interface.
+func (s *YAMLStorage) Load() (*Board, error) {
+	b, err := BoardFromFile(s.filename)
+	if err != nil {
+		return nil, err
+	}
+	s.board = b
+	return b, nil
+}
+
+// Save is part of the Storage interface.
+func (s *YAMLStorage) Save(b *Board) error {
+	s.board = b
+	return BoardToFileWithBackups(s.filename, b, s.backups.Due(time.Now()))
+}
+
+func (s *YAMLStorage) saveBoard() error {
+	if s.board == nil {
+		return fmt.Errorf("no board loaded")
+	}
+	return s.Save(s.board)
+}
+
+// AppendTaskTime is part of the Storage interface.
+func (s *YAMLStorage) AppendTaskTime(task *Task, tt *TaskTime) error {
+	return s.saveBoard()
+}
+
+// UpdateTask is part of the Storage interface.
+func (s *YAMLStorage) UpdateTask(task *Task) error {
+	return s.saveBoard()
+}
+
+// UpdateList is part of the Storage interface.
+func (s *YAMLStorage) UpdateList(list *List) error {
+	return s.saveBoard()
+}
+
+// Close is part of the Storage interface.
+func (s *YAMLStorage) Close() error {
+	return nil
+}
+
+var _ Storage = (*YAMLStorage)(nil)
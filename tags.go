@@ -0,0 +1,139 @@
+package nonota
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// reTag matches the #tag tokens of a title.
+var reTag = regexp.MustCompile(`(?:^|\s)#([^\s#]+)`)
+
+// ParseTags returns the #tag tokens found in the given string (without the
+// leading #), in the order they appear and without repetitions.
+func ParseTags(s string) []string {
+	var tags []string
+	for _, m := range reTag.FindAllStringSubmatch(s, -1) {
+		tags = appendTag(tags, m[1])
+	}
+	return tags
+}
+
+// appendTag appends the tag to the list if not there yet.
+func appendTag(tags []string, tag string) []string {
+	for _, t := range tags {
+		if t == tag {
+			return tags
+		}
+	}
+	return append(tags, tag)
+}
+
+// HasTag returns true if the task has the given tag.
+func (t *Task) HasTag(tag string) bool {
+	for _, tt := range t.Tags {
+		if tt == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// AddTag adds the given tag to the task, if it doesn't have it yet.
+func (t *Task) AddTag(tag string) {
+	t.Tags = appendTag(t.Tags, tag)
+}
+
+// RemoveTag removes the given tag from the task.
+func (t *Task) RemoveTag(tag string) {
+	tags := make([]string, 0, len(t.Tags))
+	for _, tt := range t.Tags {
+		if tt != tag {
+			tags = append(tags, tt)
+		}
+	}
+	t.Tags = tags
+}
+
+// SetTitle changes the title of the task, keeping its tags in sync with the
+// #tag tokens of the title: tokens added to the title are added as tags and
+// tokens removed from it are removed from the tags. Tags not mentioned in
+// either title are kept.
+func (t *Task) SetTitle(title string) {
+	newTags := ParseTags(title)
+	for _, tag := range ParseTags(t.Title) {
+		if !stringsContain(newTags, tag) {
+			t.RemoveTag(tag)
+		}
+	}
+	for _, tag := range newTags {
+		t.AddTag(tag)
+	}
+	t.Title = title
+}
+
+func stringsContain(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Tags returns all tags used by the tasks of the board, sorted.
+func (b *Board) Tags() []string {
+	var tags []string
+	for _, l := range b.Lists {
+		for _, t := range l.Tasks {
+			for _, tag := range t.Tags {
+				tags = appendTag(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// TotalTimeWithTag returns the total time recorded within the given period on
+// tasks with the given tag.
+func (b *Board) TotalTimeWithTag(tag string, fromTime, toTime time.Time) time.Duration {
+	var total time.Duration
+	for _, l := range b.Lists {
+		for _, t := range l.Tasks {
+			if t.HasTag(tag) {
+				total += t.TotalTime(fromTime, toTime)
+			}
+		}
+	}
+	return total
+}
+
+// TotalTimeByTag returns the total time recorded within the given period for
+// every tag. Tasks with multiple tags count towards each of them, so the sum
+// of the totals may be larger than the board total. Time of untagged tasks is
+// returned in the empty tag.
+func (b *Board) TotalTimeByTag(fromTime, toTime time.Time) map[string]time.Duration {
+	totals := make(map[string]time.Duration)
+	for _, l := range b.Lists {
+		for _, t := range l.Tasks {
+			total := t.TotalTime(fromTime, toTime)
+			if total == 0 {
+				continue
+			}
+			if len(t.Tags) == 0 {
+				totals[""] += total
+			}
+			for _, tag := range t.Tags {
+				totals[tag] += total
+			}
+		}
+	}
+	return totals
+}
+
+// FormatTag returns the tag as displayed to users (prefixed with #).
+func FormatTag(tag string) string {
+	return "#" + strings.TrimPrefix(tag, "#")
+}
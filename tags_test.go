@@ -0,0 +1,42 @@
+package nonota
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	testCases := []struct {
+		s    string
+		tags []string
+	}{
+		{"", nil},
+		{"no tags here", nil},
+		{"#one", []string{"one"}},
+		{"fix #one and #two", []string{"one", "two"}},
+		{"repeated #one #one", []string{"one"}},
+		{"not a tag: issue#10", nil},
+		{"lone # sign", nil},
+		{"#one\n#two", []string{"one", "two"}},
+	}
+
+	for i, tc := range testCases {
+		tags := ParseTags(tc.s)
+		if !reflect.DeepEqual(tags, tc.tags) {
+			t.Fatalf("%d (tc %q): expected %v found %v", i, tc.s, tc.tags, tags)
+		}
+	}
+}
+
+func TestSetTitleSyncsTags(t *testing.T) {
+	task := &Task{Title: "task #one #two", Tags: []string{"one", "two", "other"}}
+
+	task.SetTitle("task #two #three")
+	want := []string{"two", "other", "three"}
+	if !reflect.DeepEqual(task.Tags, want) {
+		t.Fatalf("expected %v found %v", want, task.Tags)
+	}
+	if task.Title != "task #two #three" {
+		t.Fatalf("unexpected title %q", task.Title)
+	}
+}
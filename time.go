@@ -1,36 +1,126 @@
 package nonota
 
 import (
+	"fmt"
+	"math/bits"
+	"strings"
 	"time"
 )
 
+// Periods (days, weeks and billing periods) are closed ranges of whole
+// seconds: the Start* functions return the first second of a period and the
+// End* functions return its last second. Every function that receives a
+// (fromTime, toTime) pair follows the same convention, so toTime is included in
+// the range along with the rest of its second.
+//
+// Periods are computed in the location of the time they are calculated for,
+// so the same board gives the same totals regardless of the time zone of the
+// machine, as long as the reference times are in the board's time zone (see
+// Settings.Location).
+//
+// Internally, ranges are handled as the half-open interval [fromTime,
+// periodEnd(toTime)), such that consecutive periods neither overlap nor leave
+// gaps between them.
+
+// periodEnd returns the instant at which the period ending at (and including)
+// toTime actually ends.
+func periodEnd(toTime time.Time) time.Time {
+	return toTime.Truncate(time.Second).Add(time.Second)
+}
+
+// scaleDuration returns d*num/den, rounded towards zero, without overflowing
+// the intermediate product. num must be in the range [0, den].
+func scaleDuration(d, num, den time.Duration) time.Duration {
+	neg := d < 0
+	if neg {
+		d = -d
+	}
+	hi, lo := bits.Mul64(uint64(d), uint64(num))
+	quo, _ := bits.Div64(hi, lo, uint64(den))
+	if neg {
+		return -time.Duration(quo)
+	}
+	return time.Duration(quo)
+}
+
 // StartOfBilling returns the time of the start of billing for a given time.
 func StartOfBilling(t time.Time) time.Time {
-	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.Local)
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
 }
 
 func EndOfBilling(t time.Time) time.Time {
-	return EndOfDay(time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.Local))
+	return EndOfDay(time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()))
 }
 
 func StartOfDay(t time.Time) time.Time {
-	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
 }
 
 func EndOfDay(t time.Time) time.Time {
-	return time.Date(t.Year(), t.Month(), t.Day(), 23, 59, 59, 0, time.Local)
+	return time.Date(t.Year(), t.Month(), t.Day(), 23, 59, 59, 0, t.Location())
 }
 
+// StartOfWeek returns the start of the Sunday-based week that contains t.
 func StartOfWeek(t time.Time) time.Time {
-	weekday := t.Weekday()
-	oneday, _ := time.ParseDuration("24h")
-	startDay := t.Add(oneday * time.Duration(weekday) * -1)
-	return StartOfDay(startDay)
+	return StartOfWeekOn(t, time.Sunday)
 }
 
+// EndOfWeek returns the end of the Sunday-based week that contains t.
 func EndOfWeek(t time.Time) time.Time {
-	weekday := t.Weekday()
-	oneday, _ := time.ParseDuration("24h")
-	endDay := t.Add(oneday * (6 - time.Duration(weekday)))
-	return EndOfDay(endDay)
-}
\ No newline at end of file
+	return EndOfWeekOn(t, time.Sunday)
+}
+
+// weekdayOffset returns how many days t is after the start of its week, when
+// weeks start on the given weekday.
+func weekdayOffset(t time.Time, first time.Weekday) int {
+	return (int(t.Weekday()) - int(first) + 7) % 7
+}
+
+// StartOfWeekOn returns the start of the week that contains t, when weeks
+// start on the given weekday.
+//
+// Days are counted on the calendar (instead of by adding multiples of 24h) so
+// that weeks containing DST transitions are handled correctly.
+func StartOfWeekOn(t time.Time, first time.Weekday) time.Time {
+	day := t.Day() - weekdayOffset(t, first)
+	return time.Date(t.Year(), t.Month(), day, 0, 0, 0, 0, t.Location())
+}
+
+// EndOfWeekOn returns the end of the week that contains t, when weeks start
+// on the given weekday.
+func EndOfWeekOn(t time.Time, first time.Weekday) time.Time {
+	day := t.Day() - weekdayOffset(t, first) + 6
+	return time.Date(t.Year(), t.Month(), day, 23, 59, 59, 0, t.Location())
+}
+
+// ISOWeek returns the ISO 8601 week of t, in the YYYY-Www format (for
+// example, 2019-W01). Note that the year is the ISO week-numbering year, which
+// may differ from the calendar year on the first and last days of the year.
+func ISOWeek(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%04d-W%02d", year, week)
+}
+
+// Weekday is a day of the week that is stored by name (for example, "monday")
+// in board files.
+type Weekday time.Weekday
+
+// MarshalYAML is part of the yaml.Marshaler interface.
+func (d Weekday) MarshalYAML() (interface{}, error) {
+	return strings.ToLower(time.Weekday(d).String()), nil
+}
+
+// UnmarshalYAML is part of the yaml.Unmarshaler interface.
+func (d *Weekday) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err != nil {
+		return err
+	}
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		if strings.EqualFold(name, wd.String()) {
+			*d = Weekday(wd)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid weekday %q", name)
+}
@@ -154,4 +154,112 @@ func TestBillTimes(t *testing.T) {
 				actualEnd)
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestWeekTimesOn(t *testing.T) {
+	const format = "2006-01-02 15:04:05"
+
+	type testCase struct {
+		first time.Weekday
+		t     string
+		start string
+		end   string
+	}
+	testCases := []testCase{
+		{time.Monday, "2019-03-23 10:20:23", "2019-03-18 00:00:00", "2019-03-24 23:59:59"},
+		{time.Monday, "2019-03-24 10:20:23", "2019-03-18 00:00:00", "2019-03-24 23:59:59"},
+		{time.Monday, "2019-03-25 00:00:00", "2019-03-25 00:00:00", "2019-03-31 23:59:59"},
+		{time.Monday, "2019-12-31 10:20:23", "2019-12-30 00:00:00", "2020-01-05 23:59:59"},
+		{time.Saturday, "2019-03-23 10:20:23", "2019-03-23 00:00:00", "2019-03-29 23:59:59"},
+		{time.Saturday, "2019-03-22 23:59:59", "2019-03-16 00:00:00", "2019-03-22 23:59:59"},
+		{time.Sunday, "2019-03-30 11:20:23", "2019-03-24 00:00:00", "2019-03-30 23:59:59"},
+	}
+
+	for i, tc := range testCases {
+		testTime, err := time.ParseInLocation(format, tc.t, time.Local)
+		if err != nil {
+			t.Fatalf("unable to decode test time %s: %v", tc.t, err)
+		}
+
+		expectedEnd, err := time.ParseInLocation(format, tc.end, time.Local)
+		if err != nil {
+			t.Fatalf("unable to decode end time %s: %v", tc.end, err)
+		}
+
+		expectedStart, err := time.ParseInLocation(format, tc.start, time.Local)
+		if err != nil {
+			t.Fatalf("unable to decode start time %s: %v", tc.start, err)
+		}
+
+		actualEnd := EndOfWeekOn(testTime, tc.first)
+		actualStart := StartOfWeekOn(testTime, tc.first)
+		if actualStart != expectedStart {
+			t.Fatalf("%d (tc %s start): expected %s found %s", i, tc.t, expectedStart,
+				actualStart)
+		}
+		if actualEnd != expectedEnd {
+			t.Fatalf("%d (tc %s end): expected %s found %s", i, tc.t, expectedEnd,
+				actualEnd)
+		}
+	}
+}
+
+// TestWeekTimesDST ensures weeks containing DST transitions start and end on
+// the correct days.
+func TestWeekTimesDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database not available: %v", err)
+	}
+	date := func(year int, month time.Month, day, hour, min, sec int) time.Time {
+		return time.Date(year, month, day, hour, min, sec, 0, loc)
+	}
+
+	type testCase struct {
+		t     time.Time
+		start time.Time
+		end   time.Time
+	}
+	testCases := []testCase{
+		// DST starts on 2019-03-10.
+		{date(2019, 3, 16, 0, 30, 0), date(2019, 3, 10, 0, 0, 0), date(2019, 3, 16, 23, 59, 59)},
+		{date(2019, 3, 10, 23, 30, 0), date(2019, 3, 10, 0, 0, 0), date(2019, 3, 16, 23, 59, 59)},
+
+		// DST ends on 2019-11-03.
+		{date(2019, 11, 3, 0, 30, 0), date(2019, 11, 3, 0, 0, 0), date(2019, 11, 9, 23, 59, 59)},
+		{date(2019, 11, 9, 23, 30, 0), date(2019, 11, 3, 0, 0, 0), date(2019, 11, 9, 23, 59, 59)},
+	}
+
+	for i, tc := range testCases {
+		actualEnd := EndOfWeek(tc.t)
+		actualStart := StartOfWeek(tc.t)
+		if !actualStart.Equal(tc.start) {
+			t.Fatalf("%d (tc %s start): expected %s found %s", i, tc.t, tc.start,
+				actualStart)
+		}
+		if !actualEnd.Equal(tc.end) {
+			t.Fatalf("%d (tc %s end): expected %s found %s", i, tc.t, tc.end,
+				actualEnd)
+		}
+	}
+}
+
+func TestISOWeek(t *testing.T) {
+	type testCase struct {
+		t    time.Time
+		week string
+	}
+	testCases := []testCase{
+		{time.Date(2019, 3, 23, 10, 0, 0, 0, time.Local), "2019-W12"},
+		{time.Date(2019, 12, 30, 10, 0, 0, 0, time.Local), "2020-W01"},
+		{time.Date(2021, 1, 3, 10, 0, 0, 0, time.Local), "2020-W53"},
+		{time.Date(2021, 1, 4, 10, 0, 0, 0, time.Local), "2021-W01"},
+	}
+
+	for i, tc := range testCases {
+		week := ISOWeek(tc.t)
+		if week != tc.week {
+			t.Fatalf("%d: expected %s found %s", i, tc.week, week)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package nonota
+
+import (
+	"fmt"
+	"time"
+)
+
+// timeIndex returns the index of the time entry in the task or -1.
+func (t *Task) timeIndex(tt *TaskTime) int {
+	for i, other := range t.Times {
+		if other == tt {
+			return i
+		}
+	}
+	return -1
+}
+
+// RemoveTaskTime removes the time entry from the task. It returns false if
+// the entry is not one of the task's.
+func (t *Task) RemoveTaskTime(tt *TaskTime) bool {
+	i := t.timeIndex(tt)
+	if i < 0 {
+		return false
+	}
+	t.Times = removeTaskTime(t.Times, i)
+	return true
+}
+
+// TaskOfTaskTime returns the task that contains the given time entry or nil.
+func (b *Board) TaskOfTaskTime(tt *TaskTime) *Task {
+	for _, l := range b.Lists {
+		for _, t := range l.Tasks {
+			if t.timeIndex(tt) >= 0 {
+				return t
+			}
+		}
+	}
+	return nil
+}
+
+// Overlaps returns true if the timeframes of both entries overlap. Entries
+// without a timeframe (End not after Start) never overlap others.
+func (tt *TaskTime) Overlaps(other *TaskTime) bool {
+	if !tt.End.After(tt.Start) || !other.End.After(other.Start) {
+		return false
+	}
+	return tt.Start.Before(other.End) && other.Start.Before(tt.End)
+}
+
+// Check returns an error if the time entry is inconsistent by itself: if it
+// ends before it starts or has a negative duration.
+//
+// Durations longer than the timeframe are allowed, as the timer records them
+// (see IssueLongDuration).
+func (tt *TaskTime) Check() error {
+	switch {
+	case tt.End.Before(tt.Start):
+		return fmt.Errorf("ends before it starts")
+	case tt.Duration < 0:
+		return fmt.Errorf("has a negative duration")
+	}
+	return nil
+}
+
+// OverlappingTaskTimes returns the entries of the board (other than tt
+// itself, matched by id) whose timeframes overlap the one of tt.
+func (b *Board) OverlappingTaskTimes(tt *TaskTime) []*TaskTime {
+	var res []*TaskTime
+	for _, l := range b.Lists {
+		for _, t := range l.Tasks {
+			for _, other := range t.Times {
+				if other == tt || (tt.ID != "" && other.ID == tt.ID) {
+					continue
+				}
+				if tt.Overlaps(other) {
+					res = append(res, other)
+				}
+			}
+		}
+	}
+	return res
+}
+
+// CheckTaskTime returns an error if the time entry is inconsistent or
+// overlaps any other entry of the board. It's meant to validate entries
+// before adding or changing them.
+func (b *Board) CheckTaskTime(tt *TaskTime) error {
+	if err := tt.Check(); err != nil {
+		return fmt.Errorf("time entry %s", err)
+	}
+	overlapping := b.OverlappingTaskTimes(tt)
+	if len(overlapping) == 0 {
+		return nil
+	}
+	other := overlapping[0]
+	desc := "another entry"
+	if t := b.TaskOfTaskTime(other); t != nil {
+		desc = fmt.Sprintf("an entry of %q", t.Title)
+	}
+	return fmt.Errorf("time entry overlaps %s (%s to %s)", desc,
+		other.Start.Format("2006-01-02 15:04"), other.End.Format("2006-01-02 15:04"))
+}
+
+// Split splits the time entry at the given time, which must be within its
+// timeframe. The entry is changed to end at that time and a new entry (with a
+// new id) is returned for the rest of the timeframe. The duration is divided
+// in proportion to the timeframes, as done for periods (see DurationWithin).
+func (tt *TaskTime) Split(at time.Time) (*TaskTime, error) {
+	if !at.After(tt.Start) || !at.Before(tt.End) {
+		return nil, fmt.Errorf("split time must be within the time entry")
+	}
+
+	first := scaleDuration(tt.Duration, at.Sub(tt.Start), tt.End.Sub(tt.Start))
+	second := &TaskTime{
+		ID:       NewID(),
+		Start:    at,
+		End:      tt.End,
+		Note:     tt.Note,
+		Duration: tt.Duration - first,
+	}
+	tt.End = at
+	tt.Duration = first
+	return second, nil
+}
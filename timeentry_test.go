@@ -0,0 +1,99 @@
+package nonota
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTaskTimeSplit(t *testing.T) {
+	start := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	tt := &TaskTime{
+		ID:        NewID(),
+		Start:     start,
+		End:       start.Add(3 * time.Hour),
+		Note:      "note",
+		Duration:  100 * time.Minute,
+		Pomodoros: 2,
+	}
+
+	if _, err := tt.Split(start); err == nil {
+		t.Fatalf("expected error splitting at the start")
+	}
+	if _, err := tt.Split(start.Add(3 * time.Hour)); err == nil {
+		t.Fatalf("expected error splitting at the end")
+	}
+
+	second, err := tt.Split(start.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tt.End.Equal(start.Add(time.Hour)) || !second.Start.Equal(tt.End) ||
+		!second.End.Equal(start.Add(3*time.Hour)) {
+		t.Fatalf("unexpected timeframes %s-%s and %s-%s", tt.Start,
+			tt.End, second.Start, second.End)
+	}
+	if tt.Duration+second.Duration != 100*time.Minute {
+		t.Fatalf("split durations %s and %s don't add up", tt.Duration,
+			second.Duration)
+	}
+	if tt.Duration != 100*time.Minute/3 {
+		t.Fatalf("unexpected first duration %s", tt.Duration)
+	}
+	if second.ID == "" || second.ID == tt.ID || second.Note != "note" ||
+		second.Pomodoros != 0 || tt.Pomodoros != 2 {
+		t.Fatalf("unexpected second entry %#v", second)
+	}
+}
+
+func TestCheckTaskTime(t *testing.T) {
+	start := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	task := &Task{ID: NewID(), Title: "task"}
+	task.AddTaskTime(&TaskTime{
+		Start:    start,
+		End:      start.Add(time.Hour),
+		Duration: time.Hour,
+	})
+	task.AddTaskTime(&TaskTime{
+		Start:    start.Add(2 * time.Hour),
+		End:      start.Add(2 * time.Hour),
+		Duration: 5 * time.Hour,
+	})
+	b := &Board{Lists: []*List{{ID: NewID(), Tasks: []*Task{task}}}}
+
+	tests := []struct {
+		name       string
+		start, end time.Duration
+		duration   time.Duration
+		valid      bool
+	}{
+		{"after", time.Hour, 2 * time.Hour, time.Hour, true},
+		{"before", -time.Hour, 0, time.Hour, true},
+		{"overlapping start", -time.Hour, time.Minute, time.Hour, false},
+		{"overlapping end", 59 * time.Minute, 2 * time.Hour, time.Hour, false},
+		{"inside", 10 * time.Minute, 20 * time.Minute, time.Minute, false},
+		{"around manual entry", 90 * time.Minute, 150 * time.Minute, time.Hour, true},
+		{"manual entry", 30 * time.Minute, 30 * time.Minute, 8 * time.Hour, true},
+		{"ends before start", 3 * time.Hour, 2 * time.Hour, 0, false},
+		// Only a warning, as the timer records entries like that.
+		{"too long", 3 * time.Hour, 4 * time.Hour, 2 * time.Hour, true},
+		{"negative", 3 * time.Hour, 4 * time.Hour, -time.Minute, false},
+	}
+	for _, tc := range tests {
+		tt := &TaskTime{
+			Start:    start.Add(tc.start),
+			End:      start.Add(tc.end),
+			Duration: tc.duration,
+		}
+		err := b.CheckTaskTime(tt)
+		if tc.valid && err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+		} else if !tc.valid && err == nil {
+			t.Errorf("%s: expected error", tc.name)
+		}
+	}
+
+	// An entry doesn't overlap itself.
+	if err := b.CheckTaskTime(task.Times[0]); err != nil {
+		t.Fatalf("entry overlaps itself: %v", err)
+	}
+}
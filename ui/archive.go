@@ -0,0 +1,163 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/matheusd/nonota"
+)
+
+const (
+	btnDelete  = "Delete"
+	btnArchive = "Archive"
+	btnCancel  = "Cancel"
+)
+
+// toggleArchiveView switches between displaying the regular board and the
+// archived items.
+func (ui *NonotaUI) toggleArchiveView() {
+	ui.showArchive = !ui.showArchive
+	ui.updateTreeTitle()
+	ui.tree.SetCurrentNode(ui.rootNode)
+	ui.recreateLists()
+}
+
+// confirmDelete asks the user to confirm the deletion of the given list or
+// task.
+func (ui *NonotaUI) confirmDelete(ref interface{}) {
+	var text string
+	switch r := ref.(type) {
+	case *nonota.List:
+		text = fmt.Sprintf("Delete the list %q and its %d tasks? "+
+			"All their recorded times will be lost.", r.Title, len(r.Tasks))
+	case *nonota.Task:
+		text = fmt.Sprintf("Delete the task %q? Its %d recorded "+
+			"times will be lost.", r.Title, len(r.Times))
+	default:
+		return
+	}
+
+	ui.showModal(text, []string{btnDelete, btnCancel}, func(label string) {
+		if label != btnDelete {
+			return
+		}
+
+		switch r := ref.(type) {
+		case *nonota.List:
+			for _, t := range r.Tasks {
+				ui.excludeWorkOnTask(t)
+			}
+			ui.history.DeleteList(ui.board, r)
+		case *nonota.Task:
+			ui.excludeWorkOnTask(r)
+			ui.history.DeleteTask(ui.board, r)
+		}
+		ui.save()
+		ui.recreateLists()
+	})
+}
+
+// confirmDeleteArchived asks the user to confirm the deletion of the given
+// item of the archive view. Only archived items may be deleted there: lists
+// that aren't archived are only displayed for their archived tasks, so only
+// those tasks are deleted.
+func (ui *NonotaUI) confirmDeleteArchived(ref interface{}) {
+	switch r := ref.(type) {
+	case *nonota.List:
+		if !r.Archived {
+			ui.confirmDeleteArchivedTasks(r)
+			return
+		}
+	case *nonota.Task:
+		l := ui.board.ListOfTask(r)
+		if !r.Archived && (l == nil || !l.Archived) {
+			return
+		}
+	}
+	ui.confirmDelete(ref)
+}
+
+// confirmDeleteArchivedTasks asks the user to confirm the deletion of the
+// archived tasks of the given list.
+func (ui *NonotaUI) confirmDeleteArchivedTasks(list *nonota.List) {
+	var archived []*nonota.Task
+	for _, t := range list.Tasks {
+		if t.Archived {
+			archived = append(archived, t)
+		}
+	}
+	if len(archived) == 0 {
+		return
+	}
+
+	text := fmt.Sprintf("Delete the %d archived tasks of the list %q? "+
+		"All their recorded times will be lost.", len(archived), list.Title)
+	ui.showModal(text, []string{btnDelete, btnCancel}, func(label string) {
+		if label != btnDelete {
+			return
+		}
+
+		for _, t := range archived {
+			ui.excludeWorkOnTask(t)
+		}
+		ui.history.DeleteArchivedTasks(ui.board, list)
+		ui.save()
+		ui.recreateLists()
+	})
+}
+
+// excludeWorkOnTask discards the work being done on the given task, if any.
+func (ui *NonotaUI) excludeWorkOnTask(task *nonota.Task) {
+	work := ui.user.WorkForTask(task)
+	if work == nil {
+		return
+	}
+	ui.user.ExcludeWork(work)
+	if ui.lastWork == work {
+		ui.lastWork = nil
+	}
+	ui.saveWorks()
+}
+
+// confirmArchive asks the user to confirm archiving the given list or task.
+func (ui *NonotaUI) confirmArchive(ref interface{}) {
+	var text string
+	switch r := ref.(type) {
+	case *nonota.List:
+		text = fmt.Sprintf("Archive the list %q and its %d tasks?",
+			r.Title, len(r.Tasks))
+	case *nonota.Task:
+		text = fmt.Sprintf("Archive the task %q?", r.Title)
+	default:
+		return
+	}
+
+	ui.showModal(text, []string{btnArchive, btnCancel}, func(label string) {
+		if label != btnArchive {
+			return
+		}
+
+		switch r := ref.(type) {
+		case *nonota.List:
+			ui.history.ArchiveList(ui.board, r)
+			ui.persist(func() error { return ui.storage.UpdateList(r) })
+		case *nonota.Task:
+			ui.history.ArchiveTask(ui.board, r)
+			ui.persist(func() error { return ui.storage.UpdateTask(r) })
+		}
+		ui.recreateLists()
+	})
+}
+
+// restore restores the given archived list or task.
+func (ui *NonotaUI) restore(ref interface{}) {
+	switch r := ref.(type) {
+	case *nonota.List:
+		ui.history.RestoreList(ui.board, r)
+		ui.persist(func() error { return ui.storage.UpdateList(r) })
+	case *nonota.Task:
+		// Restoring a task may also restore its list, so save
+		// everything.
+		ui.history.RestoreTask(ui.board, r)
+		ui.save()
+	}
+}
@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/matheusd/nonota"
+)
+
+// listVisible returns whether the given list is displayed in the tree. The
+// archive view only shows archived lists and lists with archived tasks, while
+// the regular view only shows the lists that are not archived. When filtering
+// by tag, only lists with matching tasks are displayed.
+func (ui *NonotaUI) listVisible(l *nonota.List) bool {
+	if !ui.showArchive && l.Archived {
+		return false
+	}
+	if ui.showArchive && l.Archived && ui.tagFilter == "" {
+		return true
+	}
+	for _, t := range l.Tasks {
+		if ui.taskVisible(l, t) {
+			return true
+		}
+	}
+	return !ui.showArchive && ui.tagFilter == ""
+}
+
+// taskVisible returns whether the given task of the given list is displayed
+// in the tree.
+func (ui *NonotaUI) taskVisible(l *nonota.List, t *nonota.Task) bool {
+	if ui.tagFilter != "" && !t.HasTag(ui.tagFilter) {
+		return false
+	}
+	if !ui.showArchive {
+		return !t.Archived
+	}
+	return l.Archived || t.Archived
+}
+
+// updateTreeTitle sets the title of the tree according to the current view
+// and filter.
+func (ui *NonotaUI) updateTreeTitle() {
+	title := "Lists"
+	if ui.showArchive {
+		title = "Archive (r to restore, v to go back)"
+	}
+	if ui.tagFilter != "" {
+		title += " " + nonota.FormatTag(ui.tagFilter)
+	}
+	ui.tree.SetTitle(title)
+}
+
+// promptTagFilter asks the user for the tag used to filter the tree. An empty
+// tag removes the filter.
+func (ui *NonotaUI) promptTagFilter() {
+	label := "Tag"
+	if tags := ui.board.Tags(); len(tags) > 0 {
+		for i := range tags {
+			tags[i] = nonota.FormatTag(tags[i])
+		}
+		label += " (" + strings.Join(tags, " ") + ")"
+	}
+
+	ui.prompt("Filter by tag", label, ui.tagFilter, func(tag string) {
+		ui.tagFilter = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+		ui.updateTreeTitle()
+		ui.tree.SetCurrentNode(ui.rootNode)
+		ui.recreateLists()
+	})
+}
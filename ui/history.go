@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/matheusd/nonota"
+)
+
+// loadHistory loads the undo history of the board, so that actions from
+// previous sessions can be undone.
+func (ui *NonotaUI) loadHistory() {
+	fname := nonota.HistoryFilename(ui.filename)
+	h, err := nonota.HistoryFromFile(fname, ui.board)
+	if err != nil {
+		ui.setNotice(fmt.Sprintf("Error loading undo history: %v", err))
+		h = nonota.NewHistory(ui.board)
+	}
+	ui.history = h
+}
+
+// saveHistory persists the undo history of the board.
+func (ui *NonotaUI) saveHistory() {
+	if ui.readOnly {
+		return
+	}
+
+	fname := nonota.HistoryFilename(ui.filename)
+	if err := nonota.HistoryToFile(fname, ui.history); err != nil {
+		ui.setNotice(fmt.Sprintf("Error saving undo history: %v", err))
+	}
+}
+
+// undo reverts the last action performed on the board.
+func (ui *NonotaUI) undo() {
+	a, err := ui.history.UndoLast(ui.board)
+	if err != nil {
+		ui.setNotice(err.Error())
+		return
+	}
+	ui.afterHistoryChange("Undone: " + a.Description)
+}
+
+// redo performs again the last undone action.
+func (ui *NonotaUI) redo() {
+	a, err := ui.history.RedoLast(ui.board)
+	if err != nil {
+		ui.setNotice(err.Error())
+		return
+	}
+	ui.afterHistoryChange("Redone: " + a.Description)
+}
+
+func (ui *NonotaUI) afterHistoryChange(notice string) {
+	// Works on tasks that are no longer on the board go on in copies of
+	// their tasks.
+	if orphans := ui.remapWorks(); len(orphans) > 0 {
+		notice = orphansNotice(notice, len(orphans))
+	}
+
+	ui.save()
+	ui.setNotice(notice)
+	ui.recreateLists()
+	if ui.timesTask != nil {
+		ui.remapTimesTask()
+		return
+	}
+	ui.treeNodeSelected(ui.tree.GetCurrentNode())
+}
@@ -0,0 +1,87 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gdamore/tcell"
+	"github.com/matheusd/nonota"
+)
+
+const (
+	btnKeep  = "Keep"
+	btnSplit = "Split off"
+
+	// idleNote is the note of the time entries split off from works due
+	// to idleness.
+	idleNote = "Idle"
+)
+
+// captureActivity records every keypress to detect when the user was away.
+// When returning from an idle period while a timer was running, the keypress
+// is consumed and the user is asked what to do with the idle time.
+func (ui *NonotaUI) captureActivity(event *tcell.EventKey) *tcell.EventKey {
+	// Keep the idle period pending until the current modal is dismissed.
+	if ui.hasModal() || ui.readOnly {
+		return event
+	}
+
+	period, wasIdle := ui.idle.Activity()
+	if !wasIdle || ui.lastWork == nil || ui.lastWork.IsPaused() {
+		return event
+	}
+
+	ui.askIdle(ui.lastWork, period)
+	return nil
+}
+
+// askIdle asks the user whether to keep the given idle period in the work,
+// discard it or split it off as a separate time entry of the task.
+func (ui *NonotaUI) askIdle(work *nonota.Work, period nonota.IdlePeriod) {
+	reason := "You were away"
+	if period.Suspended {
+		reason = "The computer was suspended"
+	}
+	text := fmt.Sprintf("%s for %s (since %s) while the timer for %q was "+
+		"running. What should be done with that time?", reason,
+		period.Duration().Round(time.Second), period.Start.Format("15:04"),
+		work.Task().Title)
+
+	buttons := []string{btnKeep, btnDiscard, btnSplit}
+	ui.showModal(text, buttons, func(label string) {
+		switch label {
+		case btnDiscard:
+			work.Discard(period.Duration())
+			ui.saveWorks()
+
+		case btnSplit:
+			// The time before the idle period is recorded as its own
+			// entry, so that the idle entry doesn't overlap the one
+			// the work records when stopped.
+			before, idle := work.SplitIdle(period)
+			ui.saveWorks()
+			idle.Note = idleNote
+			var tts []*nonota.TaskTime
+			if before != nil {
+				tts = append(tts, before)
+			}
+			if idle.Duration > 0 {
+				tts = append(tts, idle)
+			}
+			if len(tts) == 0 {
+				return
+			}
+			task := work.Task()
+			ui.history.AddTaskTimes(ui.board, "split idle time", task, tts...)
+			ui.recreateLists()
+			ui.persist(func() error {
+				for _, tt := range tts {
+					if err := ui.storage.AppendTaskTime(task, tt); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}
+	})
+}
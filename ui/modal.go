@@ -0,0 +1,77 @@
+package ui
+
+import (
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+const modalPage = "modal"
+
+// showModal displays a modal dialog with the given text and buttons on top of
+// the main screen. The done function is called with the label of the selected
+// button after the modal is dismissed.
+func (ui *NonotaUI) showModal(text string, buttons []string, done func(label string)) {
+	modal := tview.NewModal().
+		SetText(text).
+		AddButtons(buttons).
+		SetDoneFunc(func(_ int, label string) {
+			ui.pages.RemovePage(modalPage)
+			ui.app.SetFocus(ui.tree)
+			if done != nil {
+				done(label)
+			}
+		})
+
+	ui.pages.AddPage(modalPage, modal, false, true)
+	ui.app.SetFocus(modal)
+}
+
+// hasModal returns true if a modal dialog is currently displayed.
+func (ui *NonotaUI) hasModal() bool {
+	return ui.pages.HasPage(modalPage)
+}
+
+// showDialog displays the given form centered on top of the main screen, with
+// the given size. It's removed by calling closeDialog.
+func (ui *NonotaUI) showDialog(form *tview.Form, width, height int) {
+	grid := tview.NewGrid().
+		SetColumns(0, width, 0).
+		SetRows(0, height, 0).
+		AddItem(form, 1, 1, 1, 1, 0, 0, true)
+
+	ui.pages.AddPage(modalPage, grid, true, true)
+	ui.app.SetFocus(form)
+}
+
+// closeDialog removes the dialog displayed by showDialog.
+func (ui *NonotaUI) closeDialog() {
+	ui.pages.RemovePage(modalPage)
+	ui.app.SetFocus(ui.tree)
+}
+
+// prompt asks the user for a single line of text. The done function is only
+// called if the user accepts the input.
+func (ui *NonotaUI) prompt(title, label, value string, done func(text string)) {
+	form := tview.NewForm().AddInputField(label, value, 0, nil, nil)
+	accept := func() {
+		text := form.GetFormItem(0).(*tview.InputField).GetText()
+		ui.closeDialog()
+		done(text)
+	}
+	form.AddButton("OK", accept).
+		AddButton(btnCancel, ui.closeDialog).
+		SetCancelFunc(ui.closeDialog)
+	form.SetBorder(true).SetTitle(title)
+
+	// Accept the input when enter is pressed on the field itself. This is
+	// captured before the form gets to move the focus to the next item.
+	form.GetFormItem(0).(*tview.InputField).SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEnter {
+			accept()
+			return nil
+		}
+		return event
+	})
+
+	ui.showDialog(form, 60, 7)
+}
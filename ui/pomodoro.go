@@ -0,0 +1,98 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/matheusd/nonota"
+	"github.com/matheusd/nonota/report"
+)
+
+// ringBell rings the terminal bell.
+func ringBell() {
+	fmt.Fprint(os.Stdout, "\a")
+}
+
+// togglePomodoro starts a pomodoro on the given task (starting or resuming
+// its work as needed) or stops the current pomodoro.
+func (ui *NonotaUI) togglePomodoro(task *nonota.Task) {
+	if ui.pomodoro != nil {
+		current := ui.pomodoro.Work().Task()
+		ui.stopPomodoro()
+		if current == task {
+			return
+		}
+	}
+
+	work := ui.user.WorkForTask(task)
+	if work == nil || work.IsPaused() {
+		work = ui.user.ToggleWorkOnTask(task)
+	}
+	ui.lastWork = work
+	ui.pomodoro = nonota.StartPomodoro(work, ui.board.Settings.Pomodoro, ui.clock)
+	ui.setNotice(fmt.Sprintf("Pomodoro started on %q", task.Title))
+	ui.saveWorks()
+}
+
+// stopPomodoro stops the current pomodoro, if any. The work continues in
+// whatever state it is.
+func (ui *NonotaUI) stopPomodoro() {
+	if ui.pomodoro == nil {
+		return
+	}
+	ui.pomodoro = nil
+	ui.setNotice("Pomodoro stopped")
+}
+
+// updatePomodoro moves the current pomodoro through its phases, ringing the
+// bell at every transition.
+func (ui *NonotaUI) updatePomodoro() {
+	if ui.pomodoro == nil {
+		return
+	}
+
+	// The work was stopped or discarded.
+	work := ui.pomodoro.Work()
+	if ui.user.WorkForTask(work.Task()) != work {
+		ui.pomodoro = nil
+		return
+	}
+
+	if ui.pomodoro.Update() == 0 {
+		return
+	}
+
+	ui.bell()
+	switch phase := ui.pomodoro.Phase(); phase {
+	case nonota.PomodoroWork:
+		ui.setNotice(fmt.Sprintf("Break is over, back to %q",
+			work.Task().Title))
+	default:
+		ui.setNotice(fmt.Sprintf("Pomodoro #%d done, time for a %s",
+			ui.pomodoro.Completed(), phase))
+	}
+	ui.saveWorks()
+	ui.recreateLists()
+}
+
+// pomodoroStatus returns the status bar text of the current pomodoro.
+func (ui *NonotaUI) pomodoroStatus() string {
+	if ui.pomodoro == nil {
+		return ""
+	}
+	remaining := ui.pomodoro.Remaining().Round(time.Second)
+	return fmt.Sprintf("🍅 %s %02d:%02d (%d done) ", ui.pomodoro.Phase(),
+		int(remaining.Minutes()), int(remaining.Seconds())%60,
+		ui.pomodoro.Completed())
+}
+
+// taskPomodoros returns the number of pomodoros of the task in the given
+// report node, including the ones of its current work.
+func (ui *NonotaUI) taskPomodoros(t *nonota.Task, node *report.Node) int {
+	n := node.TotalPomodoros()
+	if work := ui.user.WorkForTask(t); work != nil {
+		n += work.Pomodoros()
+	}
+	return n
+}
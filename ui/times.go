@@ -0,0 +1,326 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell"
+	"github.com/matheusd/nonota"
+	"github.com/rivo/tview"
+)
+
+// entryTimeFormat is the format used to display and input the start and end
+// of time entries.
+const entryTimeFormat = "2006-01-02 15:04"
+
+const btnSave = "Save"
+
+// newTimesTable creates the table used to list the time entries of a task.
+func (ui *NonotaUI) newTimesTable() *tview.Table {
+	table := tview.NewTable().
+		SetSelectable(true, false).
+		SetFixed(1, 0)
+	table.SetBorder(true)
+	table.SetInputCapture(ui.timesInputCapture)
+	return table
+}
+
+// showTimes displays the time entries of the given task and moves the focus
+// to them.
+func (ui *NonotaUI) showTimes(task *nonota.Task) {
+	ui.timesTask = task
+	ui.refreshTimes()
+	ui.timesTable.Select(1, 0)
+	ui.detailPages.SwitchToPage("times")
+	ui.app.SetFocus(ui.timesTable)
+}
+
+// closeTimes hides the time entries and moves the focus back to the tree.
+func (ui *NonotaUI) closeTimes() {
+	ui.timesTask = nil
+	ui.detailPages.SwitchToPage("editor")
+	ui.treeNodeSelected(ui.tree.GetCurrentNode())
+	ui.app.SetFocus(ui.tree)
+}
+
+// refreshTimes fills the times table with the entries of the displayed task.
+func (ui *NonotaUI) refreshTimes() {
+	table := ui.timesTable
+	task := ui.timesTask
+	row, _ := table.GetSelection()
+
+	table.Clear()
+	table.SetTitle("Times: " + task.Title)
+	for col, title := range []string{"Start", "End", "Duration", "🍅", "Note"} {
+		table.SetCell(0, col, tview.NewTableCell(title).
+			SetTextColor(tcell.ColorYellow).
+			SetSelectable(false))
+	}
+
+	loc := ui.board.Settings.Location()
+	for i, tt := range task.Times {
+		pomodoros := ""
+		if tt.Pomodoros > 0 {
+			pomodoros = fmt.Sprintf("%d", tt.Pomodoros)
+		}
+		cells := []string{
+			tt.Start.In(loc).Format(entryTimeFormat),
+			tt.End.In(loc).Format(entryTimeFormat),
+			tt.Duration.Round(time.Second).String(),
+			pomodoros,
+			tt.Note,
+		}
+		for col, text := range cells {
+			table.SetCell(i+1, col, tview.NewTableCell(text).SetReference(tt))
+		}
+	}
+
+	if row >= table.GetRowCount() {
+		row = table.GetRowCount() - 1
+	}
+	if row < 1 {
+		row = 1
+	}
+	table.Select(row, 0)
+}
+
+// selectedTaskTime returns the time entry selected in the times table or nil.
+func (ui *NonotaUI) selectedTaskTime() *nonota.TaskTime {
+	row, _ := ui.timesTable.GetSelection()
+	cell := ui.timesTable.GetCell(row, 0)
+	if cell == nil {
+		return nil
+	}
+	tt, _ := cell.GetReference().(*nonota.TaskTime)
+	return tt
+}
+
+func (ui *NonotaUI) timesInputCapture(event *tcell.EventKey) *tcell.EventKey {
+	if event.Key() == tcell.KeyEscape {
+		ui.closeTimes()
+		return nil
+	}
+	if ui.readOnly {
+		return event
+	}
+
+	tt := ui.selectedTaskTime()
+	switch {
+	case event.Rune() == 'a':
+		ui.editTaskTime(nil)
+	case tt == nil:
+		return event
+	case event.Rune() == 'e' || event.Key() == tcell.KeyEnter:
+		ui.editTaskTime(tt)
+	case event.Rune() == 's':
+		ui.splitTaskTime(tt)
+	case event.Rune() == 'm':
+		ui.moveTaskTime(tt)
+	case event.Rune() == 'd':
+		ui.confirmDeleteTaskTime(tt)
+	default:
+		return event
+	}
+	return nil
+}
+
+// closeTimesDialog removes the dialog displayed over the times table.
+func (ui *NonotaUI) closeTimesDialog() {
+	ui.pages.RemovePage(modalPage)
+	ui.app.SetFocus(ui.timesTable)
+}
+
+// timesChanged saves the board after the time entries of the displayed task
+// were modified and updates the screen.
+func (ui *NonotaUI) timesChanged() {
+	ui.save()
+	ui.recreateLists()
+	ui.refreshTimes()
+}
+
+// parseEntryTime parses a time entered in a time entry form.
+func (ui *NonotaUI) parseEntryTime(name, value string) (time.Time, error) {
+	t, err := time.ParseInLocation(entryTimeFormat, strings.TrimSpace(value),
+		ui.board.Settings.Location())
+	if err != nil {
+		return t, fmt.Errorf("invalid %s time (use %s)", name, entryTimeFormat)
+	}
+	return t, nil
+}
+
+// editTaskTime shows a form to edit the given time entry, or to add a new one
+// to the displayed task if tt is nil.
+func (ui *NonotaUI) editTaskTime(tt *nonota.TaskTime) {
+	title := "Edit time"
+	entry := nonota.TaskTime{}
+	if tt != nil {
+		entry = *tt
+	} else {
+		title = "Add time"
+		now := ui.clock.Now().Truncate(time.Minute)
+		entry.Start = now.Add(-time.Hour)
+		entry.End = now
+		entry.Duration = time.Hour
+	}
+
+	loc := ui.board.Settings.Location()
+	texts := []string{
+		entry.Start.In(loc).Format(entryTimeFormat),
+		entry.End.In(loc).Format(entryTimeFormat),
+		entry.Duration.Round(time.Second).String(),
+		entry.Note,
+	}
+	form := tview.NewForm().
+		AddInputField("Start", texts[0], 0, nil, nil).
+		AddInputField("End", texts[1], 0, nil, nil).
+		AddInputField("Duration", texts[2], 0, nil, nil).
+		AddInputField("Note", texts[3], 0, nil, nil)
+	field := func(i int) string {
+		return form.GetFormItem(i).(*tview.InputField).GetText()
+	}
+
+	accept := func() {
+		// Only the fields whose text changed are parsed, as the text is
+		// less precise than the entry (it has no seconds and is in the
+		// board location), so unchanged fields keep their exact values.
+		e := entry
+		var err error
+		timeframeChanged := false
+		if field(0) != texts[0] {
+			if e.Start, err = ui.parseEntryTime("start", field(0)); err != nil {
+				ui.setNotice(err.Error())
+				return
+			}
+			timeframeChanged = true
+		}
+		if field(1) != texts[1] {
+			if e.End, err = ui.parseEntryTime("end", field(1)); err != nil {
+				ui.setNotice(err.Error())
+				return
+			}
+			timeframeChanged = true
+		}
+		switch s := strings.TrimSpace(field(2)); {
+		case s == "":
+			e.Duration = e.End.Sub(e.Start)
+		case field(2) != texts[2]:
+			if e.Duration, err = time.ParseDuration(s); err != nil {
+				ui.setNotice(fmt.Sprintf("Invalid duration %q", s))
+				return
+			}
+		}
+		e.Note = field(3)
+
+		// Existing entries are only checked against the others if their
+		// timeframe changed, so that their other fields can be edited
+		// regardless of the issues they already had.
+		if tt == nil || timeframeChanged {
+			err = ui.board.CheckTaskTime(&e)
+		} else {
+			err = e.Check()
+		}
+		if err != nil {
+			ui.setNotice(err.Error())
+			return
+		}
+
+		ui.closeTimesDialog()
+		if tt == nil {
+			ui.history.AddTaskTime(ui.board, ui.timesTask, &e)
+			ui.timesChanged()
+			ui.timesTable.Select(len(ui.timesTask.Times), 0)
+			return
+		}
+		ui.history.EditTaskTime(ui.board, ui.timesTask, tt, func(tt *nonota.TaskTime) {
+			tt.Start = e.Start
+			tt.End = e.End
+			tt.Duration = e.Duration
+			tt.Note = e.Note
+		})
+		ui.timesChanged()
+	}
+
+	form.AddButton(btnSave, accept).
+		AddButton(btnCancel, ui.closeTimesDialog).
+		SetCancelFunc(ui.closeTimesDialog)
+	form.SetBorder(true).SetTitle(title)
+	ui.showDialog(form, 60, 13)
+}
+
+// splitTaskTime asks for the time at which to split the given time entry.
+func (ui *NonotaUI) splitTaskTime(tt *nonota.TaskTime) {
+	if !tt.End.After(tt.Start) {
+		ui.setNotice("Time entries without a timeframe can't be split")
+		return
+	}
+
+	loc := ui.board.Settings.Location()
+	middle := tt.Start.Add(tt.End.Sub(tt.Start) / 2).Truncate(time.Minute)
+	ui.prompt("Split time", "Split at", middle.In(loc).Format(entryTimeFormat), func(text string) {
+		ui.app.SetFocus(ui.timesTable)
+		at, err := ui.parseEntryTime("split", text)
+		if err != nil {
+			ui.setNotice(err.Error())
+			return
+		}
+		if _, err := ui.history.SplitTaskTime(ui.board, ui.timesTask, tt, at); err != nil {
+			ui.setNotice(err.Error())
+			return
+		}
+		ui.timesChanged()
+	})
+}
+
+// moveTaskTime asks for the task to move the given time entry to.
+func (ui *NonotaUI) moveTaskTime(tt *nonota.TaskTime) {
+	var tasks []*nonota.Task
+	var options []string
+	for _, l := range ui.board.Lists {
+		if l.Archived {
+			continue
+		}
+		for _, t := range l.Tasks {
+			if t.Archived || t == ui.timesTask {
+				continue
+			}
+			tasks = append(tasks, t)
+			options = append(options, l.Title+" / "+t.Title)
+		}
+	}
+	if len(tasks) == 0 {
+		ui.setNotice("There are no other tasks to move the time to")
+		return
+	}
+
+	form := tview.NewForm().AddDropDown("Task", options, 0, nil)
+	form.AddButton("Move", func() {
+		i, _ := form.GetFormItem(0).(*tview.DropDown).GetCurrentOption()
+		ui.closeTimesDialog()
+		if i < 0 {
+			return
+		}
+		ui.history.MoveTaskTime(ui.board, ui.timesTask, tt, tasks[i])
+		ui.timesChanged()
+	}).
+		AddButton(btnCancel, ui.closeTimesDialog).
+		SetCancelFunc(ui.closeTimesDialog)
+	form.SetBorder(true).SetTitle("Move time")
+	ui.showDialog(form, 70, 7)
+}
+
+// confirmDeleteTaskTime asks the user to confirm the deletion of the given
+// time entry.
+func (ui *NonotaUI) confirmDeleteTaskTime(tt *nonota.TaskTime) {
+	text := fmt.Sprintf("Delete the time entry of %s starting at %s?",
+		tt.Duration.Round(time.Second),
+		tt.Start.In(ui.board.Settings.Location()).Format(entryTimeFormat))
+	ui.showModal(text, []string{btnDelete, btnCancel}, func(label string) {
+		ui.app.SetFocus(ui.timesTable)
+		if label != btnDelete {
+			return
+		}
+		ui.history.DeleteTaskTime(ui.board, ui.timesTask, tt)
+		ui.timesChanged()
+	})
+}
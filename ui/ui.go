@@ -2,23 +2,51 @@ package ui
 
 import (
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/gdamore/tcell"
 	"github.com/matheusd/nonota"
+	"github.com/matheusd/nonota/report"
 	"github.com/rivo/tview"
 )
 
 const codeWidth = 45
 
+// noticeDuration is how long notices are displayed in the status bar.
+const noticeDuration = 5 * time.Second
+
 type NonotaUI struct {
 	board    *nonota.Board
 	user     *nonota.User
+	clock    nonota.Clock
+	idle     *nonota.IdleDetector
+	pomodoro *nonota.Pomodoro
+	bell     func()
+	history  *nonota.History
 	app      *tview.Application
+	storage  nonota.Storage
 	refTime  time.Time
 	filename string
+	readOnly bool
+
+	// fileInfo is the info of the board file when it was last loaded or
+	// saved, used to detect modifications made by other programs.
+	fileInfo os.FileInfo
+	dirty    bool
+	conflict bool
+
+	showArchive bool
+	tagFilter   string
+
+	notice     string
+	noticeTime time.Time
 
+	worksSavedAt time.Time
+	savedWorks   int
+
+	pages       *tview.Pages
 	tree        *tview.TreeView
 	rootNode    *tview.TreeNode
 	detailPages *tview.Pages
@@ -31,9 +59,17 @@ type NonotaUI struct {
 	confirmWork  *nonota.Work
 	statusBar    *tview.TextView
 	treeNodes    map[interface{}]*tview.TreeNode
+
+	// timesTask is the task whose time entries are displayed in
+	// timesTable.
+	timesTask  *nonota.Task
+	timesTable *tview.Table
 }
 
-func New(board *nonota.Board, filename string, refTime time.Time) *NonotaUI {
+// New creates the UI to edit the given board, loaded from the given storage.
+// The filename of the board is used to detect changes made by other programs
+// and to store the state of running works.
+func New(storage nonota.Storage, board *nonota.Board, filename string, refTime time.Time) *NonotaUI {
 
 	rootNode := tview.NewTreeNode("Board").SetSelectable(true).SetReference(board)
 	tree := tview.NewTreeView().SetRoot(rootNode).SetCurrentNode(rootNode)
@@ -69,13 +105,22 @@ func New(board *nonota.Board, filename string, refTime time.Time) *NonotaUI {
 
 		//AddItem(detailPages, codeWidth, 1, false)
 
-	app := tview.NewApplication().SetRoot(root, true)
+	pages := tview.NewPages().
+		AddPage("main", root, true, true)
+
+	app := tview.NewApplication().SetRoot(pages, true)
+	clock := nonota.SystemClock
 	ui := &NonotaUI{
+		storage:      storage,
 		filename:     filename,
 		board:        board,
 		refTime:      refTime,
-		user:         &nonota.User{},
+		user:         &nonota.User{Clock: clock},
+		clock:        clock,
+		bell:         ringBell,
+		idle:         nonota.NewIdleDetector(clock, board.Settings.IdleThresholdOrDefault()),
 		app:          app,
+		pages:        pages,
 		rootNode:     rootNode,
 		tree:         tree,
 		detailPages:  detailPages,
@@ -91,6 +136,9 @@ func New(board *nonota.Board, filename string, refTime time.Time) *NonotaUI {
 		AddInputField("Duration", "", 0, nil, nil).
 		AddInputField("Note", "", 0, nil, nil)
 
+	ui.timesTable = ui.newTimesTable()
+	detailPages.AddPage("times", ui.timesTable, true, false)
+
 	editor.CancelFunc = func() {
 		ui.treeNodeSelected(tree.GetCurrentNode())
 		ui.app.SetFocus(tree)
@@ -101,7 +149,11 @@ func New(board *nonota.Board, filename string, refTime time.Time) *NonotaUI {
 		ui.app.SetFocus(tree)
 	}
 
+	ui.fileInfo = ui.statFile()
+	ui.loadHistory()
 	ui.setInputCapture()
+	app.SetInputCapture(ui.captureActivity)
+	ui.restoreWorks()
 	ui.recreateLists()
 	tree.SetChangedFunc(ui.treeNodeSelected)
 	rootNode.ExpandAll()
@@ -116,45 +168,147 @@ func New(board *nonota.Board, filename string, refTime time.Time) *NonotaUI {
 	return ui
 }
 
+// SetReadOnly sets whether the UI is allowed to modify the board. This is
+// used when the board is already open in some other nonota instance.
+func (ui *NonotaUI) SetReadOnly(readOnly bool) {
+	ui.readOnly = readOnly
+}
+
+// setNotice displays the given message in the status bar for a few seconds.
+func (ui *NonotaUI) setNotice(notice string) {
+	ui.notice = notice
+	ui.noticeTime = time.Now()
+}
+
+// save saves the whole board to the storage.
 func (ui *NonotaUI) save() {
-	err := nonota.BoardToFile(ui.filename, ui.board)
+	ui.persist(func() error {
+		return ui.storage.Save(ui.board)
+	})
+}
+
+// persist saves the changes made to the board by calling op, which should
+// use one of the incremental operations of the storage. If there are other
+// unsaved changes, the whole board is saved instead.
+func (ui *NonotaUI) persist(op func() error) {
+	if ui.readOnly {
+		return
+	}
+
+	// Never blindly overwrite changes made by some other program.
+	if ui.externallyModified() {
+		ui.dirty = true
+		ui.showConflict()
+		return
+	}
+
+	var err error
+	if ui.dirty {
+		err = ui.storage.Save(ui.board)
+	} else {
+		err = op()
+	}
 	if err != nil {
-		fmt.Println("EEEERRR", err)
+		ui.dirty = true
+		ui.setNotice(fmt.Sprintf("Error saving board: %v", err))
+		return
 	}
+	ui.dirty = false
+	ui.fileInfo = ui.statFile()
+	ui.saveHistory()
 }
 
 func (ui *NonotaUI) setInputCapture() {
 	ui.tree.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		currNode := ui.tree.GetCurrentNode()
 
+		// Switching views, filtering and listing the time entries of
+		// a task don't change the board, so they are also allowed in
+		// read-only mode.
+		switch {
+		case event.Rune() == 'v':
+			ui.toggleArchiveView()
+			return nil
+		case event.Rune() == 't':
+			ui.promptTagFilter()
+			return nil
+		case event.Rune() == 'T' && !ui.showArchive:
+			if task, ok := currNode.GetReference().(*nonota.Task); ok {
+				ui.showTimes(task)
+				return nil
+			}
+		}
+
+		if ui.readOnly {
+			// Otherwise, only navigation is allowed.
+			return event
+		}
+
+		switch {
+		case event.Rune() == 'u':
+			ui.undo()
+			return nil
+		case event.Key() == tcell.KeyCtrlR:
+			ui.redo()
+			return nil
+		}
+
+		if ui.showArchive {
+			// Archived items can only be restored or deleted.
+			ref := currNode.GetReference()
+			switch event.Rune() {
+			case 'r':
+				ui.restore(ref)
+			case 'd':
+				ui.confirmDeleteArchived(ref)
+			default:
+				return event
+			}
+			ui.app.QueueUpdateDraw(ui.recreateLists)
+			return nil
+		}
+
 		switch r := currNode.GetReference().(type) {
 		case *nonota.List:
 			switch {
+			case event.Rune() == 'd':
+				ui.confirmDelete(r)
+			case event.Rune() == 'x':
+				ui.confirmArchive(r)
 			case event.Rune() == 'J':
-				ui.board.MoveDown(r)
+				ui.history.MoveDown(ui.board, r)
 				ui.save()
 			case event.Rune() == 'K':
-				ui.board.MoveUp(r)
+				ui.history.MoveUp(ui.board, r)
 				ui.save()
 			case event.Rune() == 'i':
 				ui.app.SetFocus(ui.editor.GetPrimitive())
 			case event.Rune() == 'a':
-				ui.board.AppendNewTask(r)
+				ui.history.AppendNewTask(ui.board, r)
+				ui.dirty = true
 			default:
 				return event
 			}
 		case *nonota.Task:
 			switch {
+			case event.Rune() == 'd':
+				ui.confirmDelete(r)
+			case event.Rune() == 'x':
+				ui.confirmArchive(r)
 			case event.Rune() == 'J':
-				ui.board.MoveTaskDown(r)
+				ui.history.MoveTaskDown(ui.board, r)
 				ui.save()
 			case event.Rune() == 'K':
-				ui.board.MoveTaskUp(r)
+				ui.history.MoveTaskUp(ui.board, r)
 				ui.save()
 			case event.Rune() == 'i':
 				ui.app.SetFocus(ui.editor.GetPrimitive())
 			case event.Rune() == ' ':
+				ui.stopPomodoro()
 				ui.lastWork = ui.user.ToggleWorkOnTask(r)
+				ui.saveWorks()
+			case event.Rune() == 'p':
+				ui.togglePomodoro(r)
 			case event.Key() == tcell.KeyEnter:
 				ui.confirmToStopWork(r)
 			default:
@@ -163,9 +317,11 @@ func (ui *NonotaUI) setInputCapture() {
 		case *nonota.Board:
 			switch {
 			case event.Rune() == 'a':
-				ui.board.AppendNewList()
+				ui.history.AppendNewList(ui.board)
+				ui.dirty = true
 			case event.Rune() == 'A':
-				ui.board.PrependNewList()
+				ui.history.PrependNewList(ui.board)
+				ui.dirty = true
 			default:
 				return event
 			}
@@ -181,24 +337,59 @@ func (ui *NonotaUI) setInputCapture() {
 func (ui *NonotaUI) perSecondUpdate() {
 	var txt string
 
+	ui.idle.Tick()
+	ui.updatePomodoro()
+	ui.checkExternalChanges()
+	if time.Since(ui.worksSavedAt) >= worksSaveInterval {
+		ui.saveWorks()
+	}
+
+	if ui.readOnly {
+		txt += "[red]READ-ONLY (board open elsewhere)[-] "
+	}
+
+	txt += ui.pomodoroStatus()
+
 	if ui.lastWork != nil {
 		workTime := ui.lastWork.CurrentDuration().Round(time.Second)
-		txt += " ⌚" + workTime.String() + " " + ui.lastWork.Task.Title + "\t"
+		txt += " ⌚" + workTime.String() + " " + ui.lastWork.Task().Title
+		if ui.idle.Idle() && !ui.lastWork.IsPaused() {
+			txt += " [yellow](away)[-]"
+		}
+		txt += "\t"
 	}
 
 	now := ui.refTime
-	dayTotal := ui.board.TotalTime(nonota.StartOfDay(now), nonota.EndOfDay(now))
-	weekTotal := ui.board.TotalTime(nonota.StartOfWeek(now), nonota.EndOfWeek(now))
-	billTotal := ui.board.TotalTime(nonota.StartOfBilling(now), nonota.EndOfBilling(now))
+	settings := &ui.board.Settings
+	billing := settings.Billing
+	dayTotal := ui.totalTime(nonota.StartOfDay(now), nonota.EndOfDay(now), "")
+	weekTotal := ui.totalTime(settings.StartOfWeek(now), settings.EndOfWeek(now), "")
+	billTotal := ui.totalTime(billing.Start(now), billing.End(now), "")
 
 	txt += fmt.Sprintf("⌚ day %s week %s bill %s", dayTotal, weekTotal, billTotal)
 
+	if ui.tagFilter != "" {
+		tagTotal := ui.totalTime(billing.Start(now), billing.End(now), ui.tagFilter)
+		txt += fmt.Sprintf(" (%s %s)", nonota.FormatTag(ui.tagFilter), tagTotal)
+	}
+
+	if ui.notice != "" && time.Since(ui.noticeTime) < noticeDuration {
+		txt += " [yellow]" + tview.Escape(ui.notice) + "[-]"
+	}
+
 	if ui.statusBar.GetText(false) != txt {
 		ui.statusBar.Clear()
 		ui.statusBar.SetText(txt)
 	}
 }
 
+// totalTime returns the time recorded on the board between the given times,
+// only on tasks with the given tag if it's not empty.
+func (ui *NonotaUI) totalTime(from, to time.Time, tag string) time.Duration {
+	rep := report.New(ui.board, report.Options{From: from, To: to, Tag: tag})
+	return rep.Root.Duration
+}
+
 func (ui *NonotaUI) confirmToStopWork(task *nonota.Task) {
 	work := ui.user.WorkForTask(task)
 	if work == nil {
@@ -226,22 +417,29 @@ func (ui *NonotaUI) confirmToStopWork(task *nonota.Task) {
 		AddButton("Confirm", func() {
 			fldDuration := ui.timeForm.GetFormItem(0).(*tview.InputField)
 			fldNote := ui.timeForm.GetFormItem(1).(*tview.InputField)
+			work := ui.confirmWork
 			workTime, err := time.ParseDuration(fldDuration.GetText())
 			if err != nil {
-				ui.user.ExcludeWork(ui.confirmWork)
+				ui.user.ExcludeWork(work)
 			} else {
-				ui.confirmWork.SetNote(fldNote.GetText())
-				ui.confirmWork.AdjustWorkDuration(workTime)
-				ui.user.StopWork(ui.confirmWork)
+				work.SetNote(fldNote.GetText())
+				work.AdjustWorkDuration(workTime)
+				ui.user.StopWork(work)
+				ui.history.TaskTimeAdded(ui.board, work.Task(), work.TaskTime())
 			}
-			if ui.lastWork == ui.confirmWork {
+			if ui.lastWork == work {
 				ui.lastWork = nil
 			}
 			ui.detailPages.SwitchToPage("editor")
 			ui.recreateLists()
 			ui.confirmWork = nil
 			ui.app.SetFocus(ui.tree)
-			ui.save()
+			if err == nil {
+				ui.persist(func() error {
+					return ui.storage.AppendTaskTime(work.Task(), work.TaskTime())
+				})
+			}
+			ui.saveWorks()
 		}).
 		AddButton("Cancel", func() {
 			ui.detailPages.SwitchToPage("editor")
@@ -265,31 +463,48 @@ func (ui *NonotaUI) updateCurrentNode() {
 
 	switch r := currNode.GetReference().(type) {
 	case *nonota.List:
-		r.Title = firstLine
+		ui.history.EditList(ui.board, r, func(l *nonota.List) {
+			l.Title = firstLine
+		})
+		ui.persist(func() error { return ui.storage.UpdateList(r) })
 	case *nonota.Task:
-		r.Title = firstLine
-		r.Description = descr
+		ui.history.EditTask(ui.board, r, func(t *nonota.Task) {
+			t.SetTitle(firstLine)
+			t.Description = descr
+		})
+		ui.persist(func() error { return ui.storage.UpdateTask(r) })
 	default:
 		return
 	}
 
-	ui.save()
-
 	ui.recreateLists()
 }
 
 func (ui *NonotaUI) recreateLists() {
-	startTime := nonota.StartOfBilling(ui.refTime)
-	endTime := nonota.EndOfBilling(ui.refTime)
+	billing := ui.board.Settings.Billing
+	startTime := billing.Start(ui.refTime)
+	endTime := billing.End(ui.refTime)
+	rep := report.New(ui.board, report.Options{
+		From:   startTime,
+		To:     endTime,
+		Groups: []report.GroupBy{report.GroupList, report.GroupTask},
+	})
 
-	children := make([]*tview.TreeNode, len(ui.board.Lists))
+	children := make([]*tview.TreeNode, 0, len(ui.board.Lists))
 	var selNode *tview.TreeNode
 
+	// Items are matched by id instead of by reference so that the
+	// selection is kept after the board is reloaded.
 	currNode := ui.tree.GetCurrentNode()
-	selItem := currNode.GetReference()
+	selID := refID(currNode.GetReference())
+
+	for _, l := range ui.board.Lists {
+		if !ui.listVisible(l) {
+			continue
+		}
 
-	for i, l := range ui.board.Lists {
-		totalTime := l.TotalTime(startTime, endTime).Round(time.Minute)
+		listNode := rep.Root.Child(l.ID)
+		totalTime := listNode.TotalTime().Round(time.Minute)
 		text := l.Title
 		if totalTime > 0 {
 			totalTimeStr := totalTime.String()
@@ -305,20 +520,28 @@ func (ui *NonotaUI) recreateLists() {
 			n.SetText(text)
 		}
 
-		if l == selItem {
+		if l.ID == selID {
 			selNode = n
 		}
 
-		children[i] = n
+		children = append(children, n)
 
-		listNodes := make([]*tview.TreeNode, len(l.Tasks))
+		listNodes := make([]*tview.TreeNode, 0, len(l.Tasks))
+
+		for _, t := range l.Tasks {
+			if !ui.taskVisible(l, t) {
+				continue
+			}
 
-		for j, t := range l.Tasks {
 			text := t.Title
-			totalTime := t.TotalTime(startTime, endTime)
+			taskNode := listNode.Child(t.ID)
+			totalTime := taskNode.TotalTime()
 			if totalTime > 0 {
 				text += " ⌚" + totalTime.Round(time.Second).String()
 			}
+			if n := ui.taskPomodoros(t, taskNode); n > 0 {
+				text += fmt.Sprintf(" 🍅%d", n)
+			}
 
 			tn, has := ui.treeNodes[t]
 			if !has {
@@ -328,13 +551,13 @@ func (ui *NonotaUI) recreateLists() {
 				tn.SetText(text)
 			}
 
-			listNodes[j] = tn
+			listNodes = append(listNodes, tn)
 
-			if ui.lastWork != nil && t == ui.lastWork.Task {
+			if ui.lastWork != nil && t == ui.lastWork.Task() {
 				tn.SetColor(tcell.ColorYellow)
 			}
 
-			if t == selItem {
+			if t.ID == selID {
 				selNode = tn
 			}
 		}
@@ -345,7 +568,23 @@ func (ui *NonotaUI) recreateLists() {
 	ui.rootNode.SetChildren(children)
 	if selNode != nil {
 		ui.tree.SetCurrentNode(selNode)
+	} else {
+		// The selected item was removed or hidden.
+		ui.tree.SetCurrentNode(ui.rootNode)
+	}
+}
+
+// refID returns the id of the board item referenced by a tree node.
+func refID(ref interface{}) string {
+	switch r := ref.(type) {
+	case *nonota.Board:
+		return r.ID
+	case *nonota.List:
+		return r.ID
+	case *nonota.Task:
+		return r.ID
 	}
+	return ""
 }
 
 func (ui *NonotaUI) treeNodeSelected(node *tview.TreeNode) {
@@ -365,5 +604,7 @@ func (ui *NonotaUI) treeNodeSelected(node *tview.TreeNode) {
 }
 
 func (ui *NonotaUI) Run() error {
-	return ui.app.Run()
+	err := ui.app.Run()
+	ui.saveWorks()
+	return err
 }
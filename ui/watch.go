@@ -0,0 +1,146 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/matheusd/nonota"
+	"github.com/rivo/tview"
+)
+
+const (
+	btnReload   = "Reload from file"
+	btnKeepMine = "Keep my changes"
+)
+
+// fileChanged returns true if the file described by cur is not the same file
+// described by old, either because it was replaced or because it was modified.
+func fileChanged(old, cur os.FileInfo) bool {
+	if old == nil || cur == nil {
+		return old != cur
+	}
+	return !os.SameFile(old, cur) || !old.ModTime().Equal(cur.ModTime()) ||
+		old.Size() != cur.Size()
+}
+
+// statFile returns the current info of the board file or nil if it does not
+// exist (or can't be read).
+func (ui *NonotaUI) statFile() os.FileInfo {
+	fi, err := os.Stat(ui.filename)
+	if err != nil {
+		return nil
+	}
+	return fi
+}
+
+// externallyModified returns true if the board file was modified by someone
+// else since it was last loaded or saved by the UI.
+func (ui *NonotaUI) externallyModified() bool {
+	return fileChanged(ui.fileInfo, ui.statFile())
+}
+
+// checkExternalChanges reloads the board if its file was modified by some
+// other program. If the board also has unsaved changes, the user is asked
+// which version to keep.
+//
+// Nothing is done while a modal is displayed: showing the conflict would
+// replace the modal (dropping its callback) and reloading would swap the
+// tasks and entries it's editing. The check is repeated every second, so the
+// changes are picked up once the modal is dismissed.
+func (ui *NonotaUI) checkExternalChanges() {
+	if ui.conflict || ui.hasModal() || !ui.externallyModified() {
+		return
+	}
+
+	if !ui.dirty {
+		ui.reload()
+		return
+	}
+
+	ui.showConflict()
+}
+
+// showConflict asks the user whether to keep the local or the external
+// version of the board. While another modal is displayed, the question is
+// postponed until checkExternalChanges runs after the modal is dismissed.
+func (ui *NonotaUI) showConflict() {
+	if ui.conflict || ui.hasModal() {
+		return
+	}
+	ui.conflict = true
+
+	text := fmt.Sprintf("The board file %s was modified outside of nonota "+
+		"and there are unsaved changes. Which version should be kept?",
+		ui.filename)
+	ui.showModal(text, []string{btnReload, btnKeepMine}, func(label string) {
+		ui.conflict = false
+		switch label {
+		case btnReload:
+			ui.reload()
+		case btnKeepMine:
+			ui.fileInfo = ui.statFile()
+			ui.save()
+		}
+	})
+}
+
+// reload replaces the board with the contents of its file, remapping the
+// current works to the new tasks.
+func (ui *NonotaUI) reload() {
+	board, err := ui.storage.Load()
+	if err != nil {
+		ui.setNotice(fmt.Sprintf("Error reloading board: %v", err))
+		return
+	}
+
+	ui.fileInfo = ui.statFile()
+	ui.dirty = false
+	ui.board = board
+	ui.treeNodes = make(map[interface{}]*tview.TreeNode)
+	ui.rootNode.SetReference(board)
+
+	if orphans := ui.remapWorks(); len(orphans) > 0 {
+		ui.save()
+		ui.setNotice(orphansNotice("Board reloaded", len(orphans)))
+	} else {
+		ui.setNotice("Board reloaded from file")
+	}
+
+	ui.recreateLists()
+	ui.rootNode.ExpandAll()
+	ui.remapTimesTask()
+}
+
+// remapWorks points the current works to the tasks of the current board (see
+// nonota.User.RemapTasks) and returns the works whose tasks were recreated.
+func (ui *NonotaUI) remapWorks() []*nonota.Work {
+	if ui.confirmWork != nil {
+		if task := ui.board.TaskByID(ui.confirmWork.Task().ID); task != nil {
+			ui.confirmWork.SetTask(task)
+		}
+	}
+	return ui.user.RemapTasks(ui.board)
+}
+
+// orphansNotice returns the notice shown when the tasks of running timers were
+// recreated after the board changed.
+func orphansNotice(prefix string, orphans int) string {
+	return fmt.Sprintf("%s; %d running timer(s) moved to the %q list "+
+		"since their tasks were removed", prefix, orphans,
+		nonota.OrphansListTitle)
+}
+
+// remapTimesTask points the displayed time entries to the task with the same
+// id in the current board, closing them if the task no longer exists.
+func (ui *NonotaUI) remapTimesTask() {
+	if ui.timesTask == nil {
+		return
+	}
+	task := ui.board.TaskByID(ui.timesTask.ID)
+	if task == nil {
+		ui.closeTimes()
+		return
+	}
+	ui.timesTask = task
+	ui.refreshTimes()
+}
@@ -0,0 +1,104 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/matheusd/nonota"
+)
+
+const (
+	// worksSaveInterval is how often the state of the running works is
+	// persisted.
+	worksSaveInterval = 10 * time.Second
+
+	// minCreditGap is the minimum gap in tracking a running work (while
+	// nonota was closed) for which the user is asked whether to credit it.
+	minCreditGap = time.Minute
+
+	btnCredit  = "Credit"
+	btnDiscard = "Discard"
+)
+
+// saveWorks persists the state of the current works, so that they can be
+// restored if nonota is restarted or crashes.
+func (ui *NonotaUI) saveWorks() {
+	if ui.readOnly {
+		return
+	}
+
+	// Avoid needlessly rewriting the file while nothing is being tracked.
+	states := ui.user.WorkStates()
+	if len(states) == 0 && ui.savedWorks == 0 && !ui.worksSavedAt.IsZero() {
+		return
+	}
+
+	fname := nonota.WorksFilename(ui.filename)
+	err := nonota.WorksToFile(fname, states)
+	if err != nil {
+		ui.setNotice(fmt.Sprintf("Error saving running timers: %v", err))
+		return
+	}
+	ui.worksSavedAt = time.Now()
+	ui.savedWorks = len(states)
+}
+
+// restoreWorks recreates the works that were in progress when nonota was last
+// closed. For works that were running, the user is asked whether the time
+// nonota was closed should be credited to them.
+func (ui *NonotaUI) restoreWorks() {
+	if ui.readOnly {
+		return
+	}
+
+	fname := nonota.WorksFilename(ui.filename)
+	states, err := nonota.WorksFromFile(fname)
+	if err != nil {
+		ui.setNotice(fmt.Sprintf("Error restoring running timers: %v", err))
+		return
+	}
+
+	now := ui.clock.Now()
+	var gaps []creditGap
+	works := ui.user.RestoreWorks(ui.board, states)
+	for i, w := range works {
+		if w == nil {
+			continue
+		}
+		if !w.IsPaused() {
+			ui.lastWork = w
+		}
+
+		gap := states[i].Gap(now)
+		if gap >= minCreditGap {
+			gaps = append(gaps, creditGap{work: w, gap: gap})
+		}
+	}
+	ui.askCreditGaps(gaps)
+}
+
+// creditGap is a gap in tracking a restored work.
+type creditGap struct {
+	work *nonota.Work
+	gap  time.Duration
+}
+
+// askCreditGaps asks the user whether each of the given gaps in tracking
+// should be added to its work. Only one modal can be displayed at a time, so
+// the next gap is only asked after the previous one is decided.
+func (ui *NonotaUI) askCreditGaps(gaps []creditGap) {
+	if len(gaps) == 0 {
+		return
+	}
+	work, gap := gaps[0].work, gaps[0].gap
+	text := fmt.Sprintf("The timer for %q was running when nonota was "+
+		"closed %s ago. Credit that time to it?", work.Task().Title,
+		gap.Round(time.Second))
+	ui.showModal(text, []string{btnCredit, btnDiscard}, func(label string) {
+		if label == btnCredit {
+			work.AdjustWorkDuration(work.CurrentDuration() + gap)
+			ui.saveWorks()
+		}
+		ui.askCreditGaps(gaps[1:])
+	})
+}
@@ -1,46 +1,65 @@
 package nonota
 
+import (
+	"sync"
+)
+
+// User tracks the works in progress of the person using nonota.
+//
+// User is safe for concurrent use.
 type User struct {
-	CurrentWorks []*Work
+	// Clock is the clock used by new works. If nil, the system clock is
+	// used. It must not be changed after the user starts working.
+	Clock Clock
+
+	mu    sync.Mutex
+	works []*Work
 }
 
-func (u *User) StopWork(work *Work) error {
-	workIdx := -1
-	for i, w := range u.CurrentWorks {
+// CurrentWorks returns the works currently in progress.
+func (u *User) CurrentWorks() []*Work {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	works := make([]*Work, len(u.works))
+	copy(works, u.works)
+	return works
+}
+
+// removeWork removes the given work from the current works. It must be called
+// with the mutex held.
+func (u *User) removeWork(work *Work) {
+	for i, w := range u.works {
 		if w == work {
-			workIdx = i
-			break
+			u.works = append(u.works[:i], u.works[i+1:]...)
+			return
 		}
 	}
+}
 
-	if workIdx != -1 {
-		u.CurrentWorks = append(u.CurrentWorks[:workIdx], u.CurrentWorks[workIdx+1:]...)
-	}
+func (u *User) StopWork(work *Work) error {
+	u.mu.Lock()
+	u.removeWork(work)
+	u.mu.Unlock()
 
 	return work.StopWork()
 }
 
 func (u *User) ExcludeWork(work *Work) {
-	workIdx := -1
-	for i, w := range u.CurrentWorks {
-		if w == work {
-			workIdx = i
-			break
-		}
-	}
-
-	if workIdx != -1 {
-		u.CurrentWorks = append(u.CurrentWorks[:workIdx], u.CurrentWorks[workIdx+1:]...)
-	}
+	u.mu.Lock()
+	u.removeWork(work)
+	u.mu.Unlock()
 }
 
 // ToggleWorkOnTask either resumes (or starts) working on the given task or
 // pauses it (if already working).
 func (u *User) ToggleWorkOnTask(task *Task) *Work {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
 	var work *Work
-	for _, w := range u.CurrentWorks {
-		if w.Task == task {
-			if w.paused {
+	for _, w := range u.works {
+		if w.Task() == task {
+			if w.IsPaused() {
 				w.ResumeWork()
 			} else {
 				w.PauseWork()
@@ -52,9 +71,9 @@ func (u *User) ToggleWorkOnTask(task *Task) *Work {
 	}
 
 	if work == nil {
-		work = StartWork(task)
-		u.CurrentWorks = append(u.CurrentWorks, work)
-	} else if work.paused {
+		work = StartWorkWithClock(task, u.Clock)
+		u.works = append(u.works, work)
+	} else if work.IsPaused() {
 		return nil
 	}
 
@@ -62,10 +81,106 @@ func (u *User) ToggleWorkOnTask(task *Task) *Work {
 }
 
 func (u *User) WorkForTask(task *Task) *Work {
-	for _, w := range u.CurrentWorks {
-		if w.Task == task {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.workForTask(task)
+}
+
+func (u *User) workForTask(task *Task) *Work {
+	for _, w := range u.works {
+		if w.Task() == task {
 			return w
 		}
 	}
 	return nil
 }
+
+// OrphansListTitle is the title of the list where RemapTasks recreates the
+// tasks of works whose tasks were removed from the board.
+const OrphansListTitle = "Orphaned timers"
+
+// RemapTasks points every current work to the task with the same id in the
+// given board. This is used after the board is reloaded from its file or
+// changed by undoing or redoing actions.
+//
+// Works for tasks that no longer exist in the board keep going on a copy of
+// their task (with a new id, so that the original may still be restored by
+// redoing an action), added to the list titled OrphansListTitle, which is
+// created if needed. That way the time they tracked is not lost. The works
+// whose tasks were recreated are returned.
+func (u *User) RemapTasks(b *Board) []*Work {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	var orphans []*Work
+	var orphansList *List
+	for _, w := range u.works {
+		old := w.Task()
+		task := b.TaskByID(old.ID)
+		if task == nil {
+			if orphansList == nil {
+				orphansList = b.orphansList()
+			}
+			task = b.AppendNewTask(orphansList)
+			task.Title = old.Title
+			task.Description = old.Description
+			task.Tags = append([]string(nil), old.Tags...)
+			orphans = append(orphans, w)
+		}
+		w.SetTask(task)
+	}
+	return orphans
+}
+
+// orphansList returns the list titled OrphansListTitle, creating it if
+// needed.
+func (b *Board) orphansList() *List {
+	for _, l := range b.Lists {
+		if l.Title == OrphansListTitle && !l.Archived {
+			return l
+		}
+	}
+	l := b.AppendNewList()
+	l.Title = OrphansListTitle
+	return l
+}
+
+// WorkStates returns the state of all current works, to be persisted.
+func (u *User) WorkStates() []WorkState {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	states := make([]WorkState, len(u.works))
+	for i, w := range u.works {
+		states[i] = w.State()
+	}
+	return states
+}
+
+// RestoreWorks recreates the works of the given states as current works of the
+// user. States for tasks that no longer exist in the board (or that were
+// already recorded in it) are skipped. The
+// restored works are returned in the same order as their states (with nil
+// for skipped ones).
+func (u *User) RestoreWorks(b *Board, states []WorkState) []*Work {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	works := make([]*Work, len(states))
+	for i, st := range states {
+		task := b.TaskByID(st.TaskID)
+		if task == nil || u.workForTask(task) != nil {
+			continue
+		}
+
+		// The work may have been stopped and recorded in the board
+		// right before its state was last saved.
+		if task.TaskTimeByID(st.TimeID) != nil {
+			continue
+		}
+
+		works[i] = WorkFromState(task, st, u.Clock)
+		u.works = append(u.works, works[i])
+	}
+	return works
+}
@@ -0,0 +1,353 @@
+package nonota
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// IssueKind identifies the kind of inconsistency found on a board.
+type IssueKind string
+
+const (
+	// IssueOverlap is reported for time entries whose timeframes overlap,
+	// so the same time would be billed twice.
+	IssueOverlap IssueKind = "overlap"
+
+	// IssueLongDuration is reported for time entries with more work than
+	// their timeframe allows. It's only a warning, as the timer itself
+	// records entries like that: the time it tracks is rounded up to the
+	// next minute when stopped.
+	IssueLongDuration IssueKind = "long-duration"
+
+	// IssueNegativeDuration is reported for time entries with a negative
+	// duration.
+	IssueNegativeDuration IssueKind = "negative-duration"
+
+	// IssueEndBeforeStart is reported for time entries that end before
+	// they start.
+	IssueEndBeforeStart IssueKind = "end-before-start"
+
+	// IssueFutureTime is reported for time entries that start or end in
+	// the future.
+	IssueFutureTime IssueKind = "future-time"
+
+	// IssueEmptyTitle is reported for lists and tasks without a title.
+	IssueEmptyTitle IssueKind = "empty-title"
+
+	// IssueDuplicateID is reported for items with the same id as a
+	// previous item of the board.
+	IssueDuplicateID IssueKind = "duplicate-id"
+)
+
+// Warning returns true if issues of the kind don't prevent the board from
+// being exported (see Board.Check).
+func (k IssueKind) Warning() bool {
+	return k == IssueLongDuration
+}
+
+// Issue is an inconsistency found by Board.Validate. The item with the issue
+// is identified by the List, Task and TaskTime fields (those that apply).
+type Issue struct {
+	Kind     IssueKind
+	List     *List
+	Task     *Task
+	TaskTime *TaskTime
+
+	// Other is the entry overlapped by TaskTime in IssueOverlap issues.
+	Other *TaskTime
+
+	Description string
+}
+
+func (i Issue) String() string {
+	var where string
+	switch {
+	case i.Task != nil:
+		where = fmt.Sprintf("task %q", i.Task.Title)
+	case i.List != nil:
+		where = fmt.Sprintf("list %q", i.List.Title)
+	default:
+		where = "board"
+	}
+	if i.TaskTime != nil {
+		where += fmt.Sprintf(", entry at %s", i.TaskTime.Start.Format(issueTimeFormat))
+	}
+	return fmt.Sprintf("%s (%s): %s", where, i.Kind, i.Description)
+}
+
+const issueTimeFormat = "2006-01-02 15:04:05"
+
+// ValidationError is returned by Board.Check when the board has issues.
+type ValidationError struct {
+	Issues []Issue
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("board has %d issues (run nonota check)", len(e.Issues))
+}
+
+// Validate looks for inconsistencies in the board: overlapping time entries,
+// entries with invalid timeframes or durations or in the future, items
+// without titles and duplicate ids.
+//
+// Entries that start and end at the same time record work without a
+// timeframe, so they may have any duration and never overlap others.
+func (b *Board) Validate() []Issue {
+	return b.ValidateWithClock(SystemClock)
+}
+
+// ValidateWithClock is like Validate, but uses the given clock to detect
+// entries in the future.
+func (b *Board) ValidateWithClock(clock Clock) []Issue {
+	now := clockOrSystem(clock).Now()
+	var issues []Issue
+	ids := map[string]bool{b.ID: true}
+	checkID := func(id string, issue Issue) {
+		if ids[id] {
+			issue.Kind = IssueDuplicateID
+			issue.Description = fmt.Sprintf("id %s is already used", id)
+			issues = append(issues, issue)
+		}
+		ids[id] = true
+	}
+
+	for _, l := range b.Lists {
+		checkID(l.ID, Issue{List: l})
+		if strings.TrimSpace(l.Title) == "" {
+			issues = append(issues, Issue{Kind: IssueEmptyTitle, List: l,
+				Description: "list has no title"})
+		}
+
+		for _, t := range l.Tasks {
+			checkID(t.ID, Issue{List: l, Task: t})
+			if strings.TrimSpace(t.Title) == "" {
+				issues = append(issues, Issue{Kind: IssueEmptyTitle,
+					List: l, Task: t, Description: "task has no title"})
+			}
+
+			for _, tt := range t.Times {
+				issue := Issue{List: l, Task: t, TaskTime: tt}
+				checkID(tt.ID, issue)
+				for _, ti := range taskTimeIssues(tt, now) {
+					issue.Kind, issue.Description = ti.Kind, ti.Description
+					issues = append(issues, issue)
+				}
+			}
+		}
+	}
+
+	for _, o := range b.overlaps() {
+		descr := fmt.Sprintf("overlaps an entry of task %q from %s to %s",
+			o.otherTask.Title, o.other.Start.Format(issueTimeFormat),
+			o.other.End.Format(issueTimeFormat))
+		issues = append(issues, Issue{Kind: IssueOverlap, List: o.list,
+			Task: o.task, TaskTime: o.tt, Other: o.other, Description: descr})
+	}
+
+	return issues
+}
+
+// taskTimeIssues returns the issues of a single time entry. Only the Kind
+// and Description fields of the issues are filled.
+func taskTimeIssues(tt *TaskTime, now time.Time) []Issue {
+	var issues []Issue
+	add := func(kind IssueKind, format string, args ...interface{}) {
+		issues = append(issues, Issue{Kind: kind,
+			Description: fmt.Sprintf(format, args...)})
+	}
+
+	span := tt.End.Sub(tt.Start)
+	switch {
+	case span < 0:
+		add(IssueEndBeforeStart, "ends at %s, before it starts",
+			tt.End.Format(issueTimeFormat))
+	case span > 0 && tt.Duration > span:
+		add(IssueLongDuration, "duration %s is longer than its timeframe (%s)",
+			tt.Duration, span)
+	}
+	if tt.Duration < 0 {
+		add(IssueNegativeDuration, "duration %s is negative", tt.Duration)
+	}
+	if tt.Start.After(now) || tt.End.After(now) {
+		add(IssueFutureTime, "is in the future")
+	}
+	return issues
+}
+
+// timedEntry is a time entry of the board along with its task and list.
+type timedEntry struct {
+	list *List
+	task *Task
+	tt   *TaskTime
+}
+
+// overlap is a time entry that overlaps another that started before it.
+type overlap struct {
+	timedEntry
+	otherTask *Task
+	other     *TaskTime
+}
+
+// timedEntries returns the entries of the board that have a timeframe,
+// sorted by their start time.
+func (b *Board) timedEntries() []timedEntry {
+	var entries []timedEntry
+	for _, l := range b.Lists {
+		for _, t := range l.Tasks {
+			for _, tt := range t.Times {
+				if tt.End.After(tt.Start) {
+					entries = append(entries, timedEntry{l, t, tt})
+				}
+			}
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].tt.Start.Before(entries[j].tt.Start)
+	})
+	return entries
+}
+
+// overlaps returns every pair of overlapping entries of the board. Each pair
+// is reported once, on the entry that starts last.
+func (b *Board) overlaps() []overlap {
+	var res []overlap
+	entries := b.timedEntries()
+	for i, e := range entries {
+		for _, later := range entries[i+1:] {
+			if !later.tt.Start.Before(e.tt.End) {
+				break
+			}
+			res = append(res, overlap{later, e.task, e.tt})
+		}
+	}
+	return res
+}
+
+// Check returns a *ValidationError with the issues of the board (see
+// Validate) or nil if there are none. Warnings are not included.
+func (b *Board) Check() error {
+	var issues []Issue
+	for _, issue := range b.Validate() {
+		if !issue.Kind.Warning() {
+			issues = append(issues, issue)
+		}
+	}
+	if len(issues) == 0 {
+		return nil
+	}
+	return &ValidationError{Issues: issues}
+}
+
+// CheckPeriod is like Check, but ignores the issues of time entries outside
+// the given period. It's meant to be called before exporting the period, so
+// that old inconsistencies don't prevent exporting new time.
+func (b *Board) CheckPeriod(fromTime, toTime time.Time) error {
+	inPeriod := func(tt *TaskTime) bool {
+		return tt != nil && (tt.DurationWithin(fromTime, toTime) > 0 ||
+			(!tt.Start.Before(fromTime) && !tt.Start.After(toTime)))
+	}
+
+	var issues []Issue
+	for _, issue := range b.Validate() {
+		if issue.Kind.Warning() {
+			continue
+		}
+		if issue.TaskTime != nil && !inPeriod(issue.TaskTime) &&
+			!inPeriod(issue.Other) {
+			continue
+		}
+		issues = append(issues, issue)
+	}
+	if len(issues) == 0 {
+		return nil
+	}
+	return &ValidationError{Issues: issues}
+}
+
+// Fix fixes the issues of the board that have an unambiguous fix and
+// returns the number of changes made:
+//
+//   - Entries that end before they start are changed to end after their
+//     duration.
+//   - Negative durations are changed to zero.
+//   - Overlapping entries are trimmed to start when the previous entry ends,
+//     removing the trimmed part from their duration.
+//   - Lists and tasks without a title get the default one.
+//   - Duplicate ids are replaced by new ids.
+//
+// Entries in the future, entries that are entirely within others and
+// durations longer than the timeframe of their entries (which are only
+// warnings) are left alone, as only the user can tell which data is wrong. Validate should be
+// called afterwards to find out the remaining issues.
+func (b *Board) Fix() int {
+	var changes int
+	ids := map[string]bool{b.ID: true}
+	fixID := func(id *string) {
+		if ids[*id] {
+			*id = NewID()
+			changes++
+		}
+		ids[*id] = true
+	}
+
+	for _, l := range b.Lists {
+		fixID(&l.ID)
+		if strings.TrimSpace(l.Title) == "" {
+			l.Title = "New List"
+			changes++
+		}
+		for _, t := range l.Tasks {
+			fixID(&t.ID)
+			if strings.TrimSpace(t.Title) == "" {
+				t.Title = "New Task"
+				changes++
+			}
+			for _, tt := range t.Times {
+				fixID(&tt.ID)
+				if tt.fixDuration() {
+					changes++
+				}
+			}
+		}
+	}
+
+	// Entries are processed in order of their start times, so each one
+	// only needs to be compared to the latest end seen so far.
+	var prevEnd time.Time
+	for _, e := range b.timedEntries() {
+		tt := e.tt
+		if tt.Start.Before(prevEnd) && tt.End.After(prevEnd) {
+			// The work recorded is assumed to be evenly spread
+			// (as in DurationWithin), so the trimmed part takes
+			// its share of the duration.
+			tt.Duration -= scaleDuration(tt.Duration,
+				prevEnd.Sub(tt.Start), tt.End.Sub(tt.Start))
+			tt.Start = prevEnd
+			tt.fixDuration()
+			changes++
+		}
+		if tt.End.After(prevEnd) {
+			prevEnd = tt.End
+		}
+	}
+
+	return changes
+}
+
+// fixDuration fixes the timeframe and duration of the entry so that it ends
+// after it starts and its duration is not negative. It returns true if the
+// entry was changed.
+func (tt *TaskTime) fixDuration() bool {
+	changed := false
+	if tt.Duration < 0 {
+		tt.Duration = 0
+		changed = true
+	}
+	if tt.End.Before(tt.Start) {
+		tt.End = tt.Start.Add(tt.Duration)
+		changed = true
+	}
+	return changed
+}
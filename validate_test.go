@@ -0,0 +1,181 @@
+package nonota
+
+import (
+	"testing"
+	"time"
+)
+
+// issueKinds returns the number of issues of each kind.
+func issueKinds(issues []Issue) map[IssueKind]int {
+	kinds := make(map[IssueKind]int)
+	for _, i := range issues {
+		kinds[i.Kind]++
+	}
+	return kinds
+}
+
+func TestBoardValidate(t *testing.T) {
+	start := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start.Add(24 * time.Hour))
+	entry := func(from, to, d time.Duration) *TaskTime {
+		return &TaskTime{
+			ID:       NewID(),
+			Start:    start.Add(from),
+			End:      start.Add(to),
+			Duration: d,
+		}
+	}
+
+	t1 := &Task{ID: NewID(), Title: "first", Times: []*TaskTime{
+		entry(0, time.Hour, time.Hour),
+		// Manual entry without a timeframe.
+		entry(30*time.Minute, 30*time.Minute, 8*time.Hour),
+		entry(2*time.Hour, time.Hour, time.Minute),
+	}}
+	t2 := &Task{ID: NewID(), Title: "second", Times: []*TaskTime{
+		entry(3*time.Hour, 4*time.Hour, 2*time.Hour),
+		entry(5*time.Hour, 6*time.Hour, -time.Minute),
+	}}
+	b := &Board{ID: NewID(), Lists: []*List{{
+		ID:    NewID(),
+		Title: "list",
+		Tasks: []*Task{t1, t2},
+	}}}
+	if issues := b.ValidateWithClock(clock); len(issues) != 3 {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+	kinds := issueKinds(b.ValidateWithClock(clock))
+	want := map[IssueKind]int{
+		IssueEndBeforeStart:   1,
+		IssueLongDuration:     1,
+		IssueNegativeDuration: 1,
+	}
+	for kind, n := range want {
+		if kinds[kind] != n {
+			t.Fatalf("expected %d %s issues, got %v", n, kind, kinds)
+		}
+	}
+
+	// Add every other kind of issue.
+	t3 := &Task{ID: t1.ID, Title: " ", Times: []*TaskTime{
+		entry(30*time.Minute, 90*time.Minute, time.Hour),
+		entry(40*time.Minute, 50*time.Minute, time.Minute),
+		entry(25*time.Hour, 26*time.Hour, time.Hour),
+	}}
+	b.Lists = append(b.Lists, &List{ID: NewID(), Tasks: []*Task{t3}})
+	kinds = issueKinds(b.ValidateWithClock(clock))
+	want = map[IssueKind]int{
+		IssueEndBeforeStart:   1,
+		IssueLongDuration:     1,
+		IssueNegativeDuration: 1,
+		IssueEmptyTitle:       2,
+		IssueDuplicateID:      1,
+		IssueFutureTime:       1,
+		IssueOverlap:          3,
+	}
+	for kind, n := range want {
+		if kinds[kind] != n {
+			t.Fatalf("expected %d %s issues, got %v", n, kind, kinds)
+		}
+	}
+
+	// Only the future entry and the entry within another one can't be
+	// fixed. Long durations are only warnings, so they are kept.
+	if changes := b.Fix(); changes == 0 {
+		t.Fatalf("no changes made by Fix")
+	}
+	kinds = issueKinds(b.ValidateWithClock(clock))
+	want = map[IssueKind]int{IssueFutureTime: 1, IssueOverlap: 1,
+		IssueLongDuration: 1}
+	if len(kinds) != len(want) {
+		t.Fatalf("unexpected issues after fix %v", kinds)
+	}
+	for kind, n := range want {
+		if kinds[kind] != n {
+			t.Fatalf("expected %d %s issues after fix, got %v", n, kind, kinds)
+		}
+	}
+
+	// The overlapping entry was trimmed to start after the first one.
+	tt := t3.Times[0]
+	if !tt.Start.Equal(start.Add(time.Hour)) || tt.Duration != 30*time.Minute {
+		t.Fatalf("unexpected trimmed entry %s %s", tt.Start, tt.Duration)
+	}
+	tt = t1.Times[2]
+	if !tt.End.Equal(tt.Start.Add(time.Minute)) {
+		t.Fatalf("unexpected fixed end %s", tt.End)
+	}
+	if t3.ID == t1.ID {
+		t.Fatalf("duplicate id was not replaced")
+	}
+}
+
+func TestCheckTimerEntry(t *testing.T) {
+	start := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+	task := &Task{ID: NewID(), Title: "task"}
+	b := &Board{ID: NewID(), Lists: []*List{{
+		ID:    NewID(),
+		Title: "list",
+		Tasks: []*Task{task},
+	}}}
+
+	// Record the entry the same way the UI does when stopping the timer:
+	// the duration is rounded up to the next minute.
+	w := StartWorkWithClock(task, clock)
+	clock.Advance(10*time.Minute + 20*time.Second)
+	w.AdjustWorkDuration(w.CurrentDuration().Round(time.Minute) + time.Minute)
+	if err := w.StopWork(); err != nil {
+		t.Fatal(err)
+	}
+	tt := task.Times[0]
+	if span := tt.End.Sub(tt.Start); tt.Duration <= span {
+		t.Fatalf("expected duration %s longer than span %s", tt.Duration, span)
+	}
+
+	issues := b.ValidateWithClock(clock)
+	if len(issues) != 1 || issues[0].Kind != IssueLongDuration {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+	if err := b.Check(); err != nil {
+		t.Fatalf("unexpected error checking warnings: %v", err)
+	}
+	d := tt.Duration
+	if b.Fix() != 0 || tt.Duration != d {
+		t.Fatalf("Fix changed the recorded duration to %s", tt.Duration)
+	}
+}
+
+func TestCheckPeriod(t *testing.T) {
+	day := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	entry := func(from, to time.Duration) *TaskTime {
+		return &TaskTime{ID: NewID(), Start: day.Add(from), End: day.Add(to),
+			Duration: to - from}
+	}
+	task := &Task{ID: NewID(), Title: "task", Times: []*TaskTime{
+		// Overlapping entries in March.
+		entry(0, time.Hour),
+		entry(30*time.Minute, 90*time.Minute),
+	}}
+	b := &Board{ID: NewID(), Lists: []*List{{
+		ID:    NewID(),
+		Title: "list",
+		Tasks: []*Task{task},
+	}}}
+
+	april := time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC)
+	if err := b.CheckPeriod(april, EndOfBilling(april)); err != nil {
+		t.Fatalf("unexpected error checking other period: %v", err)
+	}
+	march := StartOfBilling(day)
+	if err := b.CheckPeriod(march, EndOfBilling(march)); err == nil {
+		t.Fatalf("expected error checking the period of the overlap")
+	}
+
+	// Board level issues are reported for every period.
+	task.Times = nil
+	task.Title = ""
+	if err := b.CheckPeriod(april, EndOfBilling(april)); err == nil {
+		t.Fatalf("expected error for empty title")
+	}
+}
@@ -2,99 +2,291 @@ package nonota
 
 import (
 	"fmt"
+	"sync"
 	"time"
 )
 
+// Work tracks the time spent working on a task. The duration of the work is
+// computed from its clock whenever needed, so there's no background timer.
+//
+// Work is safe for concurrent use.
 type Work struct {
-	Task *Task
+	mu    sync.Mutex
+	clock Clock
+	task  *Task
 
-	workTime  TaskTime
-	stopChan  chan struct{}
-	paused    bool
-	workEnded bool
+	// workTime.Duration is the duration accumulated up to runningSince
+	// (or up to when the work was last paused).
+	workTime     TaskTime
+	running      bool
+	runningSince time.Time
+	workEnded    bool
 }
 
+// NewWork returns a work on the given task whose timer is not running.
 func NewWork(task *Task) *Work {
 	return &Work{
-		Task: task,
+		clock: SystemClock,
+		task:  task,
 		workTime: TaskTime{
-			Start: time.Now(),
+			ID:    NewID(),
+			Start: SystemClock.Now(),
 		},
-		stopChan: make(chan struct{}),
 	}
 }
 
+// StartWork starts working on the given task, using the system clock.
 func StartWork(task *Task) *Work {
-	w := &Work{
-		Task: task,
+	return StartWorkWithClock(task, SystemClock)
+}
+
+// StartWorkWithClock starts working on the given task, using the given clock
+// to track the time.
+func StartWorkWithClock(task *Task, clock Clock) *Work {
+	clock = clockOrSystem(clock)
+	now := clock.Now()
+	return &Work{
+		clock: clock,
+		task:  task,
 		workTime: TaskTime{
-			Start:    time.Now(),
+			ID:       NewID(),
+			Start:    now,
 			Duration: time.Minute,
 		},
-		stopChan: make(chan struct{}),
+		running:      true,
+		runningSince: now,
 	}
+}
 
-	go w.handler()
+// WorkFromState recreates a work on the given task from its persisted state.
+// The work timer is started again, unless the work was paused. A nil clock
+// means the system clock.
+func WorkFromState(task *Task, st WorkState, clock Clock) *Work {
+	clock = clockOrSystem(clock)
+	w := &Work{
+		clock: clock,
+		task:  task,
+		workTime: TaskTime{
+			ID:        st.TimeID,
+			Start:     st.Start,
+			Note:      st.Note,
+			Duration:  st.Duration,
+			Pomodoros: st.Pomodoros,
+		},
+		running: !st.Paused,
+	}
+	if w.workTime.ID == "" {
+		w.workTime.ID = NewID()
+	}
+	if w.running {
+		w.runningSince = clock.Now()
+	}
 
 	return w
 }
 
-func (w *Work) handler() {
-	ticker := time.NewTicker(time.Second)
-	lastTickerStart := time.Now()
-
-out:
-	for {
-		select {
-		case <-ticker.C:
-			now := time.Now()
-			if w.paused {
-				lastTickerStart = now
-				continue
-			}
-
-			w.workTime.Duration += now.Sub(lastTickerStart)
-			lastTickerStart = now
-		case <-w.stopChan:
-			break out
-		}
+// Task returns the task being worked on.
+func (w *Work) Task() *Task {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.task
+}
+
+// SetTask changes the task being worked on. This is used when the board is
+// reloaded and the task is replaced by its new instance.
+func (w *Work) SetTask(task *Task) {
+	w.mu.Lock()
+	w.task = task
+	w.mu.Unlock()
+}
+
+// State returns the current state of the work, to be persisted.
+func (w *Work) State() WorkState {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.clock.Now()
+	return WorkState{
+		TaskID:    w.task.ID,
+		TimeID:    w.workTime.ID,
+		Start:     w.workTime.Start,
+		Duration:  w.duration(now),
+		Note:      w.workTime.Note,
+		Pomodoros: w.workTime.Pomodoros,
+		Paused:    !w.running,
+		SavedAt:   now,
+	}
+}
+
+// duration returns the duration of the work up to the given time. It must be
+// called with the mutex held.
+func (w *Work) duration(now time.Time) time.Duration {
+	d := w.workTime.Duration
+	if w.running && now.After(w.runningSince) {
+		d += now.Sub(w.runningSince)
 	}
+	return d
+}
 
-	ticker.Stop()
+// stop stops the timer of the work, accumulating the time it ran. It must be
+// called with the mutex held.
+func (w *Work) stop(now time.Time) {
+	w.workTime.Duration = w.duration(now)
+	w.running = false
 }
 
+// StopWork stops the work and records its time entry in the task.
 func (w *Work) StopWork() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
 	if w.workEnded {
 		return fmt.Errorf("work already stopped")
 	}
 	w.workEnded = true
 
-	if w.stopChan != nil {
-		close(w.stopChan)
-		w.workTime.End = time.Now()
-	} else {
-		w.workTime.End = w.workTime.Start
-	}
-	w.Task.AddTaskTime(&w.workTime)
+	now := w.clock.Now()
+	w.stop(now)
+	w.workTime.End = now
+	w.task.AddTaskTime(&w.workTime)
 	return nil
 }
 
+// AdjustWorkDuration replaces the duration tracked so far by the given one.
+// A running work keeps accumulating time from then on.
 func (w *Work) AdjustWorkDuration(newDuration time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	w.workTime.Duration = newDuration
+	if w.running {
+		w.runningSince = w.clock.Now()
+	}
+}
+
+// Discard removes up to the given duration from the work (but never makes it
+// negative). It returns how much was actually removed.
+func (w *Work) Discard(d time.Duration) time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.clock.Now()
+	current := w.duration(now)
+	if d > current {
+		d = current
+	}
+	w.workTime.Duration = current - d
+	if w.running {
+		w.runningSince = now
+	}
+	return d
 }
 
+// SplitIdle splits the given idle period off the work. The time tracked
+// before the period is returned as a separate entry (nil if the period started
+// before the work) and the idle time (limited to the time tracked) as another
+// one, neither of them added to the task yet. The work goes on with a new
+// entry that starts when the period ends, so the three entries don't overlap.
+func (w *Work) SplitIdle(period IdlePeriod) (before, idle *TaskTime) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.clock.Now()
+	current := w.duration(now)
+	d := period.Duration()
+	if d > current {
+		d = current
+	}
+
+	start := period.Start
+	if period.Start.After(w.workTime.Start) {
+		before = &TaskTime{
+			ID:        w.workTime.ID,
+			Start:     w.workTime.Start,
+			End:       period.Start,
+			Duration:  current - d,
+			Note:      w.workTime.Note,
+			Pomodoros: w.workTime.Pomodoros,
+		}
+	} else {
+		start = w.workTime.Start
+	}
+	idle = &TaskTime{
+		ID:       NewID(),
+		Start:    start,
+		End:      period.End,
+		Duration: d,
+	}
+
+	next := TaskTime{ID: NewID(), Start: period.End}
+	if before == nil {
+		// Nothing was split off before the period, so the work keeps
+		// its entry and the time tracked outside of the period.
+		next = w.workTime
+		next.Start = period.End
+		next.Duration = current - d
+	}
+	w.workTime = next
+	if w.running {
+		w.runningSince = now
+	}
+	return before, idle
+}
+
+// CompletePomodoro records a completed pomodoro in the work's time entry.
+func (w *Work) CompletePomodoro() {
+	w.mu.Lock()
+	w.workTime.Pomodoros++
+	w.mu.Unlock()
+}
+
+// Pomodoros returns the number of pomodoros completed in the work.
+func (w *Work) Pomodoros() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.workTime.Pomodoros
+}
+
+// PauseWork pauses the timer of the work.
 func (w *Work) PauseWork() {
-	w.paused = true
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.running {
+		w.stop(w.clock.Now())
+	}
 }
 
+// ResumeWork resumes the timer of a paused work.
 func (w *Work) ResumeWork() {
-	w.paused = false
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.running && !w.workEnded {
+		w.running = true
+		w.runningSince = w.clock.Now()
+	}
+}
+
+// IsPaused returns true if the timer of the work is not running.
+func (w *Work) IsPaused() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !w.running
 }
 
+// CurrentDuration returns the duration of the work so far.
 func (w *Work) CurrentDuration() time.Duration {
-	return w.workTime.Duration
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.duration(w.clock.Now())
+}
+
+// TaskTime returns the time entry recorded by the work. It's only added to the
+// task (and its duration only updated) once the work is stopped.
+func (w *Work) TaskTime() *TaskTime {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return &w.workTime
 }
 
 func (w *Work) SetNote(note string) {
+	w.mu.Lock()
 	w.workTime.Note = note
+	w.mu.Unlock()
 }
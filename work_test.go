@@ -0,0 +1,268 @@
+package nonota
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWorkTimer(t *testing.T) {
+	start := time.Date(2019, 3, 20, 10, 0, 0, 0, time.Local)
+	clock := NewManualClock(start)
+	task := &Task{ID: NewID()}
+
+	checkDuration := func(w *Work, expected time.Duration) {
+		t.Helper()
+		if d := w.CurrentDuration(); d != expected {
+			t.Fatalf("expected duration %s found %s", expected, d)
+		}
+	}
+
+	// New works start with an extra minute.
+	w := StartWorkWithClock(task, clock)
+	checkDuration(w, time.Minute)
+	clock.Advance(10 * time.Minute)
+	checkDuration(w, 11*time.Minute)
+
+	// Paused works don't accumulate time.
+	w.PauseWork()
+	if !w.IsPaused() {
+		t.Fatalf("work should be paused")
+	}
+	clock.Advance(5 * time.Minute)
+	checkDuration(w, 11*time.Minute)
+	w.PauseWork()
+	checkDuration(w, 11*time.Minute)
+
+	w.ResumeWork()
+	if w.IsPaused() {
+		t.Fatalf("work should not be paused")
+	}
+	clock.Advance(2 * time.Minute)
+	checkDuration(w, 13*time.Minute)
+
+	// Adjusted works keep accumulating time from the new duration.
+	w.AdjustWorkDuration(30 * time.Minute)
+	checkDuration(w, 30*time.Minute)
+	clock.Advance(time.Minute)
+	checkDuration(w, 31*time.Minute)
+
+	state := w.State()
+	if state.Duration != 31*time.Minute || state.Paused ||
+		!state.SavedAt.Equal(clock.Now()) {
+		t.Fatalf("unexpected state %#v", state)
+	}
+
+	if err := w.StopWork(); err != nil {
+		t.Fatal(err)
+	}
+	if len(task.Times) != 1 {
+		t.Fatalf("expected 1 time entry found %d", len(task.Times))
+	}
+	tt := task.Times[0]
+	if !tt.Start.Equal(start) || !tt.End.Equal(start.Add(18*time.Minute)) ||
+		tt.Duration != 31*time.Minute {
+		t.Fatalf("unexpected time entry %#v", tt)
+	}
+
+	// Stopped works don't change anymore.
+	clock.Advance(time.Hour)
+	w.ResumeWork()
+	checkDuration(w, 31*time.Minute)
+	if err := w.StopWork(); err == nil {
+		t.Fatalf("expected an error when stopping the work twice")
+	}
+}
+
+func TestWorkSplitIdle(t *testing.T) {
+	start := time.Date(2019, 3, 20, 10, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+	task := &Task{ID: NewID(), Title: "task"}
+	b := &Board{ID: NewID(), Lists: []*List{{
+		ID:    NewID(),
+		Title: "list",
+		Tasks: []*Task{task},
+	}}}
+
+	// Work for 30 minutes, then go away for 20.
+	w := StartWorkWithClock(task, clock)
+	clock.Advance(50 * time.Minute)
+	period := IdlePeriod{Start: start.Add(30 * time.Minute), End: clock.Now()}
+	before, idle := w.SplitIdle(period)
+	if before == nil || !before.End.Equal(period.Start) ||
+		before.Duration != 31*time.Minute {
+		t.Fatalf("unexpected entry before the idle period %#v", before)
+	}
+	if !idle.Start.Equal(period.Start) || !idle.End.Equal(period.End) ||
+		idle.Duration != 20*time.Minute {
+		t.Fatalf("unexpected idle entry %#v", idle)
+	}
+	task.AddTaskTime(before)
+	task.AddTaskTime(idle)
+
+	// The work goes on after the idle period.
+	clock.Advance(10 * time.Minute)
+	if d := w.CurrentDuration(); d != 10*time.Minute {
+		t.Fatalf("unexpected duration after the split %s", d)
+	}
+	w.AdjustWorkDuration(w.CurrentDuration().Round(time.Minute) + time.Minute)
+	if err := w.StopWork(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, issue := range b.ValidateWithClock(clock) {
+		if !issue.Kind.Warning() {
+			t.Fatalf("unexpected issue after split: %s", issue)
+		}
+	}
+	if total := b.TotalTime(start, start.Add(time.Hour)); total != 62*time.Minute {
+		t.Fatalf("unexpected total time %s", total)
+	}
+}
+
+func TestUserRemapTasks(t *testing.T) {
+	clock := NewManualClock(time.Date(2019, 3, 20, 10, 0, 0, 0, time.UTC))
+	taskA := &Task{ID: NewID(), Title: "A", Tags: []string{"tag"}}
+	taskB := &Task{ID: NewID(), Title: "B"}
+	user := &User{Clock: clock}
+	workA := user.ToggleWorkOnTask(taskA)
+	workB := user.ToggleWorkOnTask(taskB)
+	clock.Advance(10 * time.Minute)
+
+	// The board was reloaded: task B is a new instance and task A was
+	// removed.
+	newB := &Task{ID: taskB.ID, Title: "B"}
+	b := &Board{ID: NewID(), Lists: []*List{{
+		ID:    NewID(),
+		Title: "list",
+		Tasks: []*Task{newB},
+	}}}
+	orphans := user.RemapTasks(b)
+	if len(orphans) != 1 || orphans[0] != workA {
+		t.Fatalf("unexpected orphans %v", orphans)
+	}
+	if workB.Task() != newB {
+		t.Fatalf("work B was not remapped")
+	}
+	if len(user.CurrentWorks()) != 2 {
+		t.Fatalf("orphaned work was removed from the user")
+	}
+
+	// Work A goes on in a copy of its task, so its time (only the
+	// initial minute, as it was paused when starting B) is recorded.
+	if len(b.Lists) != 2 || b.Lists[1].Title != OrphansListTitle {
+		t.Fatalf("unexpected lists %v", b.Lists)
+	}
+	copyA := b.Lists[1].Tasks[0]
+	if workA.Task() != copyA || copyA.ID == taskA.ID || copyA.Title != "A" ||
+		!copyA.HasTag("tag") {
+		t.Fatalf("unexpected copy of task A %#v", copyA)
+	}
+	if err := user.StopWork(workA); err != nil {
+		t.Fatal(err)
+	}
+	if len(copyA.Times) != 1 || copyA.Times[0].Duration != time.Minute {
+		t.Fatalf("unexpected times of the copy %v", copyA.Times)
+	}
+
+	// Further orphans reuse the list.
+	user.RemapTasks(&Board{Lists: b.Lists[1:]})
+	if workB.Task().ID == taskB.ID || len(b.Lists[1].Tasks) != 2 {
+		t.Fatalf("orphans list was not reused")
+	}
+}
+
+func TestUserToggleWork(t *testing.T) {
+	start := time.Date(2019, 3, 20, 10, 0, 0, 0, time.Local)
+	clock := NewManualClock(start)
+	taskA := &Task{ID: NewID()}
+	taskB := &Task{ID: NewID()}
+	board := &Board{Lists: []*List{{Tasks: []*Task{taskA, taskB}}}}
+	user := &User{Clock: clock}
+
+	workA := user.ToggleWorkOnTask(taskA)
+	if workA == nil || workA.Task() != taskA {
+		t.Fatalf("expected work on task A")
+	}
+	clock.Advance(10 * time.Minute)
+
+	// Working on another task pauses the first one.
+	workB := user.ToggleWorkOnTask(taskB)
+	if workB == nil || workB.Task() != taskB {
+		t.Fatalf("expected work on task B")
+	}
+	if !workA.IsPaused() {
+		t.Fatalf("work on task A should be paused")
+	}
+	clock.Advance(5 * time.Minute)
+
+	// Toggling the current work pauses it.
+	if w := user.ToggleWorkOnTask(taskB); w != nil {
+		t.Fatalf("expected no work after pausing task B")
+	}
+	if !workB.IsPaused() {
+		t.Fatalf("work on task B should be paused")
+	}
+	clock.Advance(time.Hour)
+
+	if d := workA.CurrentDuration(); d != 11*time.Minute {
+		t.Fatalf("unexpected duration of task A %s", d)
+	}
+	if d := workB.CurrentDuration(); d != 6*time.Minute {
+		t.Fatalf("unexpected duration of task B %s", d)
+	}
+
+	// Restoring the works from their states keeps the durations.
+	states := user.WorkStates()
+	restored := &User{Clock: clock}
+	works := restored.RestoreWorks(board, states)
+	if len(works) != 2 || len(restored.CurrentWorks()) != 2 {
+		t.Fatalf("expected 2 restored works")
+	}
+	clock.Advance(time.Hour)
+	if d := works[0].CurrentDuration(); d != 11*time.Minute || !works[0].IsPaused() {
+		t.Fatalf("unexpected restored work on task A (%s)", d)
+	}
+
+	if err := user.StopWork(workA); err != nil {
+		t.Fatal(err)
+	}
+	if len(user.CurrentWorks()) != 1 || user.WorkForTask(taskA) != nil {
+		t.Fatalf("work on task A was not removed")
+	}
+}
+
+// TestWorkConcurrentUse exercises a work from several goroutines. It's meant to
+// be run with the race detector.
+func TestWorkConcurrentUse(t *testing.T) {
+	clock := NewManualClock(time.Date(2019, 3, 20, 10, 0, 0, 0, time.Local))
+	task := &Task{ID: NewID()}
+	user := &User{Clock: clock}
+	w := user.ToggleWorkOnTask(task)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				w.CurrentDuration()
+				w.State()
+				user.WorkStates()
+				clock.Advance(time.Second)
+			}
+		}()
+	}
+	for j := 0; j < 100; j++ {
+		user.ToggleWorkOnTask(task)
+		w.AdjustWorkDuration(w.CurrentDuration())
+	}
+	wg.Wait()
+
+	if err := user.StopWork(w); err != nil {
+		t.Fatal(err)
+	}
+	if len(task.Times) != 1 {
+		t.Fatalf("expected 1 time entry found %d", len(task.Times))
+	}
+}